@@ -0,0 +1,31 @@
+package delegation
+
+import (
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete expired delegations",
+	Long: "Delete expired delegations from the store. Set repo.delegation.gc_on_start " +
+		"in the config file to do this automatically each time buff runs.",
+	Args: cobra.NoArgs,
+	RunE: cli.FXCommand(doGC),
+}
+
+func init() {
+	gcCmd.Flags().Bool("json", false, "Output JSON")
+}
+
+func doGC(cmd *cobra.Command, delegationStore dlgstore.Store) error {
+	n, err := delegationStore.GC(cmd.Context())
+	cobra.CheckErr(err)
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		return printJSON(cmd, map[string]int{"removed": n})
+	}
+	cmd.Printf("🧹 removed %d expired delegation(s)\n", n)
+	return nil
+}
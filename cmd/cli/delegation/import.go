@@ -0,0 +1,225 @@
+package delegation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	ucanlib "github.com/alanshaw/libracha/ucan"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/container"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/alanshaw/ucantone/validator"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file|->",
+	Short: "Import delegations",
+	Long: "Import delegations from a file, or from stdin if the file is \"-\". The " +
+		"input may be a single encoded delegation, a UCAN container (raw or base64, " +
+		"optionally gzipped) or a CAR of delegations. Delegations are verified " +
+		"before they are stored, and delegations that are not addressed to the " +
+		"current identity are rejected unless they are proofs for one that is.",
+	Args: cobra.ExactArgs(1),
+	RunE: cli.FXCommand(doImport),
+}
+
+func init() {
+	importCmd.Flags().Bool("force", false, "Import delegations that are not addressed to the current identity")
+}
+
+func doImport(cmd *cobra.Command, args []string, id principal.Signer, delegationStore dlgstore.Store) error {
+	var (
+		input []byte
+		err   error
+	)
+	if args[0] == "-" {
+		input, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		input, err = os.ReadFile(args[0])
+	}
+	cobra.CheckErr(err)
+
+	dlgs, err := decodeDelegations(input)
+	if err != nil {
+		return fmt.Errorf("decoding delegations: %w", err)
+	}
+	if len(dlgs) == 0 {
+		return errors.New("no delegations found in input")
+	}
+
+	for _, dlg := range dlgs {
+		if err := verify(dlg); err != nil {
+			return fmt.Errorf("invalid delegation %s: %w", dlg.Link(), err)
+		}
+	}
+
+	force, _ := cmd.Flags().GetBool("force")
+	var imports []ucan.Delegation
+	for _, dlg := range dlgs {
+		if dlg.Audience().DID() == id.DID() || force {
+			imports = append(imports, dlg)
+		}
+	}
+
+	// delegations in the input are considered along with the stored delegations
+	// when building proof chains, so that proofs can be imported alongside.
+	matcher := matchers{memMatcher(dlgs), delegationStore}
+	proofs := map[string]ucan.Delegation{}
+	for _, dlg := range imports {
+		if dlg.Subject() == nil || dlg.Subject().DID() == dlg.Issuer().DID() {
+			continue
+		}
+		chain, _, err := ucanlib.ProofChain(cmd.Context(), matcher, dlg.Issuer(), dlg.Command(), dlg.Subject())
+		if err != nil {
+			return fmt.Errorf("building proof chain for %s: %w", dlg.Link(), err)
+		}
+		if len(chain) == 0 {
+			return fmt.Errorf("invalid delegation %s: no proof that %s can delegate %q on %s", dlg.Link(), dlg.Issuer().DID(), dlg.Command(), dlg.Subject().DID())
+		}
+		for _, p := range chain {
+			proofs[p.Link().String()] = p
+		}
+	}
+
+	imported := map[string]struct{}{}
+	for _, dlg := range imports {
+		imported[dlg.Link().String()] = struct{}{}
+	}
+	var rejected []ucan.Delegation
+	for _, dlg := range dlgs {
+		_, isImport := imported[dlg.Link().String()]
+		_, isProof := proofs[dlg.Link().String()]
+		if !isImport && !isProof {
+			rejected = append(rejected, dlg)
+		}
+	}
+	if len(rejected) > 0 {
+		for _, dlg := range rejected {
+			cmd.PrintErrf("⛔ %s is addressed to %s\n", dlg.Link(), dlg.Audience().DID())
+		}
+		return fmt.Errorf("%d delegation(s) not addressed to %s, use --force to import anyway", len(rejected), id.DID())
+	}
+
+	for _, dlg := range imports {
+		err := delegationStore.Put(cmd.Context(), dlg)
+		cobra.CheckErr(err)
+		delete(proofs, dlg.Link().String())
+
+		sub := "any subject"
+		if dlg.Subject() != nil {
+			sub = dlg.Subject().DID().String()
+		}
+		cmd.Printf("📥 imported %s: %q on %s from %s\n", dlg.Link(), dlg.Command(), sub, dlg.Issuer().DID())
+	}
+	for _, p := range proofs {
+		err := delegationStore.Put(cmd.Context(), p)
+		cobra.CheckErr(err)
+	}
+	if len(proofs) > 0 {
+		cmd.Printf("🔗 stored %d proof(s)\n", len(proofs))
+	}
+	return nil
+}
+
+// decodeDelegations decodes a UCAN container, a single delegation or a CAR of
+// delegations.
+func decodeDelegations(input []byte) ([]ucan.Delegation, error) {
+	if len(input) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	switch input[0] {
+	case container.Base64, container.Base64url, container.Base64Gzip, container.Base64urlGzip:
+		// text containers are likely to have been copied with trailing whitespace
+		input = bytes.TrimSpace(input)
+		fallthrough
+	case container.Raw, container.RawGzip:
+		ct, err := container.Decode(input)
+		if err != nil {
+			return nil, fmt.Errorf("decoding container: %w", err)
+		}
+		return ct.Delegations(), nil
+	}
+
+	if dlg, err := delegation.Decode(input); err == nil {
+		return []ucan.Delegation{dlg}, nil
+	}
+
+	cr, err := car.NewReader(bytes.NewReader(input))
+	if err != nil {
+		return nil, errors.New("unrecognized format, expected a delegation, UCAN container or CAR")
+	}
+	var dlgs []ucan.Delegation
+	for {
+		blk, err := cr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("reading CAR: %w", err)
+		}
+		dlg, err := delegation.Decode(blk.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("decoding delegation %s: %w", blk.Link(), err)
+		}
+		dlgs = append(dlgs, dlg)
+	}
+	return dlgs, nil
+}
+
+// verify checks the delegation is signed by its issuer and has not expired.
+func verify(dlg ucan.Delegation) error {
+	verifier, err := validator.ParsePrincipal(dlg.Issuer().DID().String())
+	if err != nil {
+		return fmt.Errorf("parsing issuer: %w", err)
+	}
+	if err := validator.VerifyDelegationSignature(dlg, verifier); err != nil {
+		return err
+	}
+	return validator.ValidateNotExpired(dlg)
+}
+
+// memMatcher matches delegations in a slice.
+type memMatcher []ucan.Delegation
+
+func (m memMatcher) Match(ctx context.Context, aud ucan.Principal, cmd ucan.Command, sub ucan.Principal) iter.Seq2[ucan.Delegation, error] {
+	return func(yield func(ucan.Delegation, error) bool) {
+		for _, dlg := range m {
+			if dlg.Audience().DID() != aud.DID() || !dlg.Command().Proves(cmd) {
+				continue
+			}
+			// powerline delegations match any subject
+			if dlg.Subject() != nil && (sub == nil || dlg.Subject().DID() != sub.DID()) {
+				continue
+			}
+			if !yield(dlg, nil) {
+				return
+			}
+		}
+	}
+}
+
+// matchers matches delegations using each of the matchers in turn.
+type matchers []ucanlib.DelegationMatcher
+
+func (ms matchers) Match(ctx context.Context, aud ucan.Principal, cmd ucan.Command, sub ucan.Principal) iter.Seq2[ucan.Delegation, error] {
+	return func(yield func(ucan.Delegation, error) bool) {
+		for _, m := range ms {
+			for dlg, err := range m.Match(ctx, aud, cmd, sub) {
+				if !yield(dlg, err) || err != nil {
+					return
+				}
+			}
+		}
+	}
+}
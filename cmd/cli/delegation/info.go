@@ -0,0 +1,99 @@
+package delegation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/spf13/cobra"
+)
+
+// info is the decoded form of a delegation, used for output.
+type info struct {
+	CID      string `json:"cid"`
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// Subject is empty for powerline delegations.
+	Subject    string          `json:"subject,omitempty"`
+	Command    string          `json:"command"`
+	Policy     json.RawMessage `json:"policy"`
+	Nonce      string          `json:"nonce,omitempty"`
+	NotBefore  *uint64         `json:"nbf,omitempty"`
+	Expiration *uint64         `json:"exp,omitempty"`
+	Expired    bool            `json:"expired"`
+	Metadata   map[string]any  `json:"metadata,omitempty"`
+}
+
+func newInfo(dlg ucan.Delegation) info {
+	i := info{
+		CID:        dlg.Link().String(),
+		Issuer:     dlg.Issuer().DID().String(),
+		Audience:   dlg.Audience().DID().String(),
+		Command:    dlg.Command().String(),
+		Policy:     json.RawMessage("[]"),
+		NotBefore:  dlg.NotBefore(),
+		Expiration: dlg.Expiration(),
+		Expired:    ucan.IsExpired(dlg),
+		Metadata:   dlg.Metadata(),
+	}
+	if dlg.Subject() != nil {
+		i.Subject = dlg.Subject().DID().String()
+	}
+	if m, ok := dlg.Policy().(json.Marshaler); ok {
+		if b, err := m.MarshalJSON(); err == nil {
+			i.Policy = b
+		}
+	}
+	if len(dlg.Nonce()) > 0 {
+		i.Nonce = base64.StdEncoding.EncodeToString(dlg.Nonce())
+	}
+	return i
+}
+
+// subject describes the subject of the delegation for humans.
+func (i info) subject() string {
+	if i.Subject == "" {
+		return "any (powerline)"
+	}
+	return i.Subject
+}
+
+func printJSON(cmd *cobra.Command, v any) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatTimestamp(ts *uint64) string {
+	if ts == nil {
+		return "never"
+	}
+	return time.Unix(int64(*ts), 0).Format(time.RFC3339)
+}
+
+func (i info) print(cmd *cobra.Command) {
+	cmd.Printf("🎫 %s\n", i.CID)
+	cmd.Printf("   issuer:     %s\n", i.Issuer)
+	cmd.Printf("   audience:   %s\n", i.Audience)
+	cmd.Printf("   subject:    %s\n", i.subject())
+	cmd.Printf("   command:    %s\n", i.Command)
+	cmd.Printf("   policy:     %s\n", i.Policy)
+	if i.Nonce != "" {
+		cmd.Printf("   nonce:      %s\n", i.Nonce)
+	}
+	if i.NotBefore != nil {
+		cmd.Printf("   not before: %s\n", formatTimestamp(i.NotBefore))
+	}
+	expiry := formatTimestamp(i.Expiration)
+	if i.Expired {
+		expiry += " (expired)"
+	}
+	cmd.Printf("   expires:    %s\n", expiry)
+	for _, k := range slices.Sorted(maps.Keys(i.Metadata)) {
+		cmd.Printf("   meta.%s: %s\n", k, fmt.Sprint(i.Metadata[k]))
+	}
+}
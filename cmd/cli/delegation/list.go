@@ -0,0 +1,127 @@
+package delegation
+
+import (
+	"iter"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List stored delegations",
+	Long: "List stored delegations. By default delegations addressed to the " +
+		"current identity are listed. If --issuer or --subject is set without " +
+		"--audience, delegations for any audience are listed. Delegations issued " +
+		"by \"space share\" are only listed with --issued.",
+	Args: cobra.NoArgs,
+	RunE: cli.FXCommand(doList),
+}
+
+func init() {
+	listCmd.Flags().Bool("issued", false, "List delegations issued by \"space share\" instead of held delegations")
+	listCmd.Flags().String("audience", "", "List delegations addressed to this DID instead of the current identity")
+	listCmd.Flags().String("issuer", "", "Only list delegations issued by this DID")
+	listCmd.Flags().String("subject", "", "Only list delegations for this subject DID, or \"null\" for powerline delegations")
+	listCmd.Flags().String("command", "", "Only list delegations for this command")
+	listCmd.Flags().Bool("expired", false, "Only list expired delegations, or unexpired delegations if false")
+	listCmd.Flags().Bool("json", false, "Output JSON")
+}
+
+func doList(cmd *cobra.Command, id principal.Signer, delegationStore dlgstore.Store) error {
+	var (
+		filters []func(ucan.Delegation) bool
+		aud     ucan.Principal
+		iss     ucan.Principal
+		sub     ucan.Principal
+		anySub  = true
+	)
+	if s, _ := cmd.Flags().GetString("audience"); s != "" {
+		d, err := did.Parse(s)
+		cobra.CheckErr(err)
+		aud = d
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return dlg.Audience().DID() == d
+		})
+	}
+	if s, _ := cmd.Flags().GetString("issuer"); s != "" {
+		d, err := did.Parse(s)
+		cobra.CheckErr(err)
+		iss = d
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return dlg.Issuer().DID() == d
+		})
+	}
+	if s, _ := cmd.Flags().GetString("subject"); s == dlgstore.NullSubject {
+		anySub = false
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return dlg.Subject() == nil
+		})
+	} else if s != "" {
+		d, err := did.Parse(s)
+		cobra.CheckErr(err)
+		sub, anySub = d, false
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return dlg.Subject() != nil && dlg.Subject().DID() == d
+		})
+	}
+	if s, _ := cmd.Flags().GetString("command"); s != "" {
+		c, err := command.Parse(s)
+		cobra.CheckErr(err)
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return dlg.Command() == c
+		})
+	}
+	if cmd.Flags().Changed("expired") {
+		expired, _ := cmd.Flags().GetBool("expired")
+		filters = append(filters, func(dlg ucan.Delegation) bool {
+			return ucan.IsExpired(dlg) == expired
+		})
+	}
+
+	// query the most specific index available
+	var dlgs iter.Seq2[ucan.Delegation, error]
+	issued, _ := cmd.Flags().GetBool("issued")
+	switch {
+	case issued:
+		dlgs = delegationStore.ListIssued(cmd.Context())
+	case aud != nil:
+		dlgs = delegationStore.List(cmd.Context(), aud)
+	case iss != nil:
+		dlgs = delegationStore.FindByIssuer(cmd.Context(), iss)
+	case !anySub:
+		dlgs = delegationStore.FindBySubject(cmd.Context(), sub)
+	default:
+		dlgs = delegationStore.List(cmd.Context(), id)
+	}
+
+	infos := []info{}
+outer:
+	for dlg, err := range dlgs {
+		cobra.CheckErr(err)
+		for _, f := range filters {
+			if !f(dlg) {
+				continue outer
+			}
+		}
+		infos = append(infos, newInfo(dlg))
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		return printJSON(cmd, infos)
+	}
+	if len(infos) == 0 {
+		cmd.Println("No delegations found")
+		return nil
+	}
+	for _, i := range infos {
+		i.print(cmd)
+	}
+	return nil
+}
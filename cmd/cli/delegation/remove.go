@@ -0,0 +1,41 @@
+package delegation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/buff/pkg/store"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:     "remove <cid>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a stored delegation",
+	Args:    cobra.ExactArgs(1),
+	RunE:    cli.FXCommand(doRemove),
+}
+
+func init() {
+	removeCmd.Flags().Bool("json", false, "Output JSON")
+}
+
+func doRemove(cmd *cobra.Command, args []string, delegationStore dlgstore.Store) error {
+	root, err := cid.Parse(args[0])
+	cobra.CheckErr(err)
+
+	err = delegationStore.Del(cmd.Context(), root)
+	if errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("delegation not found: %s", root)
+	}
+	cobra.CheckErr(err)
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		return printJSON(cmd, map[string]string{"removed": root.String()})
+	}
+	cmd.Printf("🗑️ removed delegation %s\n", root)
+	return nil
+}
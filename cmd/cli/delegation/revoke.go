@@ -0,0 +1,83 @@
+package delegation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/client"
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/buff/pkg/store"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	ucanlib "github.com/alanshaw/libracha/ucan"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+)
+
+var revokeCmd = &cobra.Command{
+	Use:   "revoke <cid>",
+	Short: "Revoke a delegation",
+	Long: "Revoke a delegation issued by the current identity, or by a delegate " +
+		"of the current identity. The revocation is sent to the upload service and " +
+		"recorded locally so the delegation is no longer used as a proof. The " +
+		"delegation must be in the store, delegations created by \"space share\" " +
+		"are stored automatically and can be listed with \"delegation list --issued\".",
+	Args: cobra.ExactArgs(1),
+	RunE: cli.FXCommand(doRevoke),
+}
+
+func init() {
+	revokeCmd.Flags().Bool("local", false, "Only record the revocation locally, do not send it to the upload service")
+}
+
+func doRevoke(cmd *cobra.Command, args []string, id principal.Signer, c *client.Client, delegationStore dlgstore.Store) error {
+	root, err := cid.Parse(args[0])
+	cobra.CheckErr(err)
+
+	dlg, err := delegationStore.GetIssued(cmd.Context(), root)
+	if errors.Is(err, store.ErrNotFound) {
+		dlg, err = delegationStore.Get(cmd.Context(), root)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("delegation not found: %s, import it with \"delegation import --force\"", root)
+	}
+	cobra.CheckErr(err)
+
+	revoked, err := delegationStore.IsRevoked(cmd.Context(), root)
+	cobra.CheckErr(err)
+	if revoked {
+		cmd.Printf("✅ delegation already revoked: %s\n", root)
+		return nil
+	}
+
+	var path []ucan.Delegation
+	if dlg.Issuer().DID() != id.DID() {
+		if dlg.Subject() == nil {
+			return fmt.Errorf("delegation %s was not issued by %s", root, id.DID())
+		}
+		chain, _, err := ucanlib.ProofChain(cmd.Context(), delegationStore, dlg.Issuer(), dlg.Command(), dlg.Subject())
+		cobra.CheckErr(err)
+		for i, p := range chain {
+			if p.Issuer().DID() == id.DID() {
+				path = chain[:i+1]
+				break
+			}
+		}
+		if path == nil {
+			return fmt.Errorf("delegation %s was not issued by %s or a delegate", root, id.DID())
+		}
+	}
+
+	if local, _ := cmd.Flags().GetBool("local"); !local {
+		if err := c.Revoke(cmd.Context(), dlg, path); err != nil {
+			return fmt.Errorf("revoking delegation: %w", err)
+		}
+		cmd.Printf("📣 revocation sent to upload service\n")
+	}
+
+	err = delegationStore.Revoke(cmd.Context(), root)
+	cobra.CheckErr(err)
+	cmd.Printf("🚫 revoked delegation %s\n", root)
+	return nil
+}
@@ -0,0 +1,132 @@
+package delegation
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/client"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/alanshaw/buff/pkg/testutil"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/spf13/cobra"
+)
+
+func newRevokeCmd(t *testing.T, local bool) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("local", local, "")
+	cmd.SetContext(context.Background())
+	cmd.SetOut(io.Discard)
+	return cmd
+}
+
+func TestRevoke(t *testing.T) {
+	testCases := []struct {
+		name string
+		// issue stores the delegation to revoke, issued by id or by a delegate
+		// of id, and returns it
+		issue func(t *testing.T, id principal.Signer, store dlgstore.Store) ucan.Delegation
+		local bool
+	}{
+		{
+			name: "issued",
+			issue: func(t *testing.T, id principal.Signer, store dlgstore.Store) ucan.Delegation {
+				space := generate(t)
+				audience := generate(t)
+				dlg, err := delegation.Delegate(id, audience.DID(), space, command.Top(), delegation.WithNoExpiration())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.PutIssued(context.Background(), dlg); err != nil {
+					t.Fatal(err)
+				}
+				return dlg
+			},
+		},
+		{
+			name: "issued by delegate",
+			issue: func(t *testing.T, id principal.Signer, store dlgstore.Store) ucan.Delegation {
+				ctx := context.Background()
+				space := generate(t)
+				delegate := generate(t)
+				audience := generate(t)
+				for _, p := range []struct{ iss, aud principal.Signer }{{space, id}, {id, delegate}} {
+					dlg, err := delegation.Delegate(p.iss, p.aud.DID(), space, command.Top(), delegation.WithNoExpiration())
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err := store.Put(ctx, dlg); err != nil {
+						t.Fatal(err)
+					}
+				}
+				dlg, err := delegation.Delegate(delegate, audience.DID(), space, command.Top(), delegation.WithNoExpiration())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Put(ctx, dlg); err != nil {
+					t.Fatal(err)
+				}
+				return dlg
+			},
+		},
+		{
+			name: "local",
+			issue: func(t *testing.T, id principal.Signer, store dlgstore.Store) ucan.Delegation {
+				dlg, err := delegation.Delegate(id, generate(t).DID(), generate(t), command.Top(), delegation.WithNoExpiration())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.PutIssued(context.Background(), dlg); err != nil {
+					t.Fatal(err)
+				}
+				return dlg
+			},
+			local: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := testutil.NewUploadService(t)
+			id := generate(t)
+			store := dlgstore.NewDSDelegationStore(dssync.MutexWrap(datastore.NewMapDatastore()))
+			c, err := client.New(id, svc.Config(), store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dlg := tc.issue(t, id, store)
+			if err := doRevoke(newRevokeCmd(t, tc.local), []string{dlg.Link().String()}, id, c, store); err != nil {
+				t.Fatal(err)
+			}
+
+			if svc.Revoked(dlg.Link()) == tc.local {
+				t.Fatalf("revoked by service: %t, expected %t", svc.Revoked(dlg.Link()), !tc.local)
+			}
+			revoked, err := store.IsRevoked(ctx, dlg.Link())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !revoked {
+				t.Fatal("delegation was not marked revoked in the local store")
+			}
+		})
+	}
+}
+
+func generate(t *testing.T) principal.Signer {
+	t.Helper()
+	s, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,19 @@
+package delegation
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "delegation",
+	Short: "Manage delegations",
+}
+
+func init() {
+	Cmd.AddCommand(gcCmd)
+	Cmd.AddCommand(importCmd)
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(removeCmd)
+	Cmd.AddCommand(revokeCmd)
+	Cmd.AddCommand(showCmd)
+}
@@ -0,0 +1,40 @@
+package delegation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/buff/pkg/store"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <cid>",
+	Short: "Show the details of a stored delegation",
+	Args:  cobra.ExactArgs(1),
+	RunE:  cli.FXCommand(doShow),
+}
+
+func init() {
+	showCmd.Flags().Bool("json", false, "Output JSON")
+}
+
+func doShow(cmd *cobra.Command, args []string, delegationStore dlgstore.Store) error {
+	root, err := cid.Parse(args[0])
+	cobra.CheckErr(err)
+
+	dlg, err := delegationStore.Get(cmd.Context(), root)
+	if errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("delegation not found: %s", root)
+	}
+	cobra.CheckErr(err)
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		return printJSON(cmd, newInfo(dlg))
+	}
+	newInfo(dlg).print(cmd)
+	return nil
+}
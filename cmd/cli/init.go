@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/alanshaw/buff/pkg/config/lib"
+	"github.com/alanshaw/buff/pkg/presets"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// keyFileName is the name of the identity key file created by init, in the
+// same directory as the config file.
+const keyFileName = "identity.pem"
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up buff for first use",
+	Long: "Create the user config file, generate an identity key, create the data " +
+		"directory and print the agent DID. An existing setup is not changed " +
+		"unless --force is given, in which case the network, key file and data " +
+		"directory are updated in the existing config file, leaving other settings " +
+		"as they are, and an existing identity key is kept.",
+	Args: cobra.NoArgs,
+	RunE: doInit,
+}
+
+func init() {
+	initCmd.Flags().String("network", string(presets.Dev), fmt.Sprintf("Network to use, one of %q", presets.AvailableNetworks))
+	initCmd.Flags().Bool("encrypt", false, "Encrypt the identity key with a passphrase, read from BUFF_PASSPHRASE or the terminal")
+	initCmd.Flags().Bool("force", false, "Update an existing config file, keeping an existing identity key")
+}
+
+func doInit(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	encrypt, _ := cmd.Flags().GetBool("encrypt")
+	networkStr, _ := cmd.Flags().GetString("network")
+	network, err := presets.ParseNetwork(networkStr)
+	if err != nil {
+		return err
+	}
+
+	cfgPath, _ := cmd.Flags().GetString("config")
+	if cfgPath == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return fmt.Errorf("finding user config directory: %w", err)
+		}
+		cfgPath = filepath.Join(configDir, configFilePath)
+	}
+	if _, err := os.Stat(cfgPath); err == nil && !force {
+		return fmt.Errorf("config file already exists: %s, use --force to update it", cfgPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	if keyFile == "" {
+		keyFile = filepath.Join(filepath.Dir(cfgPath), keyFileName)
+	}
+	keyFile, err = filepath.Abs(keyFile)
+	cobra.CheckErr(err)
+
+	var id principal.Signer
+	if _, err := os.Stat(keyFile); err == nil {
+		if !force {
+			return fmt.Errorf("key file already exists: %s, use --force to set up buff with this key", keyFile)
+		}
+		id, err = lib.SignerFromEd25519PEMFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("reading existing key file: %w", err)
+		}
+		cmd.Printf("🔑 using existing identity key: %s\n", keyFile)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		var passphrase []byte
+		if encrypt {
+			passphrase, err = lib.NewPassphrase()
+			cobra.CheckErr(err)
+		}
+		id, err = ed25519.Generate()
+		cobra.CheckErr(err)
+		err = lib.WriteEd25519PEMFile(keyFile, id, passphrase, false)
+		cobra.CheckErr(err)
+		cmd.Printf("🔑 generated identity key: %s\n", keyFile)
+	} else {
+		return err
+	}
+
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	dataDir, err = filepath.Abs(dataDir)
+	cobra.CheckErr(err)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	cmd.Printf("📁 created data directory: %s\n", dataDir)
+
+	// settings other than those set by init are kept
+	cfg := viper.New()
+	cfg.SetConfigFile(cfgPath)
+	if err := cfg.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading existing config file: %w", err)
+	}
+	cfg.Set("network", network.String())
+	cfg.Set("identity.key_file", keyFile)
+	cfg.Set("repo.data_dir", dataDir)
+	if err := cfg.WriteConfigAs(cfgPath); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	cmd.Printf("💾 wrote config to %s\n", cfgPath)
+
+	cmd.Println("")
+	cmd.Println("Agent DID:")
+	cmd.Println(id.DID())
+	return nil
+}
@@ -0,0 +1,21 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/spf13/cobra"
+)
+
+var didCmd = &cobra.Command{
+	Use:   "did",
+	Short: "Print the DID of the configured identity key",
+	Args:  cobra.NoArgs,
+	RunE:  cli.FXCommand(doDID),
+}
+
+func doDID(cmd *cobra.Command, args []string, id principal.Signer) error {
+	fmt.Fprintln(cmd.OutOrStdout(), id.DID())
+	return nil
+}
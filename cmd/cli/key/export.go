@@ -0,0 +1,32 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the configured identity key",
+	Long: "Print the configured identity key in the given format. The exported " +
+		"key is not encrypted, keep it secret.",
+	Args: cobra.NoArgs,
+	RunE: cli.FXCommand(doExport),
+}
+
+func init() {
+	exportCmd.Flags().String("format", formatPEM, fmt.Sprintf("Format to export the key in, one of %q", formats))
+}
+
+func doExport(cmd *cobra.Command, args []string, id principal.Signer) error {
+	format, _ := cmd.Flags().GetString("format")
+	s, err := encodeKey(id, format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), s)
+	return nil
+}
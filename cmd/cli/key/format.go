@@ -0,0 +1,75 @@
+package key
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/alanshaw/buff/pkg/config/lib"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/algorand/go-algorand-sdk/mnemonic"
+	"github.com/multiformats/go-multibase"
+)
+
+// Key formats.
+const (
+	// formatPEM is an ed25519 PKCS#8 PEM, as used for identity.key_file.
+	formatPEM = "pem"
+	// formatMultibase is the base64 multibase encoded, multiformats tagged
+	// private key e.g. "Mg...".
+	formatMultibase = "multibase"
+	// formatMnemonic is a 25 word phrase, as printed for space recovery.
+	formatMnemonic = "mnemonic"
+)
+
+var formats = []string{formatPEM, formatMultibase, formatMnemonic}
+
+func encodeKey(signer principal.Signer, format string) (string, error) {
+	switch format {
+	case formatPEM:
+		b, err := lib.EncodeEd25519PEM(signer, nil)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	case formatMultibase:
+		return multibase.Encode(multibase.Base64pad, signer.Bytes())
+	case formatMnemonic:
+		return mnemonic.FromKey(signer.Raw())
+	default:
+		return "", fmt.Errorf("unknown format %q, expected one of %q", format, formats)
+	}
+}
+
+// decodeKey decodes a key in the format, or detects the format if it is empty.
+func decodeKey(input []byte, format string) (principal.Signer, error) {
+	input = bytes.TrimSpace(input)
+	if format == "" {
+		format = detectFormat(input)
+	}
+	switch format {
+	case formatPEM:
+		return lib.SignerFromEd25519PEM(input)
+	case formatMultibase:
+		return ed25519.Parse(string(input))
+	case formatMnemonic:
+		key, err := mnemonic.ToKey(strings.Join(strings.Fields(string(input)), " "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mnemonic: %w", err)
+		}
+		return ed25519.FromRaw(key)
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected one of %q", format, formats)
+	}
+}
+
+func detectFormat(input []byte) string {
+	if bytes.HasPrefix(input, []byte("-----BEGIN")) {
+		return formatPEM
+	}
+	if len(bytes.Fields(input)) > 1 {
+		return formatMnemonic
+	}
+	return formatMultibase
+}
@@ -0,0 +1,64 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/config/lib"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new identity key",
+	Long: "Generate a new ed25519 identity key, written as a PKCS#8 PEM to the " +
+		"output file, or to stdout if no output file is given. The file can be " +
+		"used as identity.key_file.",
+	Args: cobra.NoArgs,
+	RunE: doGenerate,
+}
+
+func init() {
+	addWriteFlags(generateCmd)
+}
+
+func doGenerate(cmd *cobra.Command, args []string) error {
+	signer, err := ed25519.Generate()
+	cobra.CheckErr(err)
+	return writeKey(cmd, signer)
+}
+
+func addWriteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", "Path to write the key to")
+	cmd.Flags().Bool("encrypt", false, "Encrypt the key with a passphrase, read from BUFF_PASSPHRASE or the terminal")
+	cmd.Flags().Bool("force", false, "Overwrite the output file if it exists")
+}
+
+// writeKey writes the key as a PKCS#8 PEM to the output file, or to stdout.
+func writeKey(cmd *cobra.Command, signer principal.Signer) error {
+	output, _ := cmd.Flags().GetString("output")
+	encrypt, _ := cmd.Flags().GetBool("encrypt")
+	force, _ := cmd.Flags().GetBool("force")
+
+	var passphrase []byte
+	if encrypt {
+		p, err := lib.NewPassphrase()
+		cobra.CheckErr(err)
+		passphrase = p
+	}
+
+	if output == "" {
+		b, err := lib.EncodeEd25519PEM(signer, passphrase)
+		cobra.CheckErr(err)
+		cmd.PrintErrf("🔑 %s\n", signer.DID())
+		fmt.Fprint(cmd.OutOrStdout(), string(b))
+		return nil
+	}
+
+	err := lib.WriteEd25519PEMFile(output, signer, passphrase, force)
+	cobra.CheckErr(err)
+	cmd.Printf("🔑 %s\n", signer.DID())
+	cmd.Printf("💾 wrote key to %s\n", output)
+	return nil
+}
@@ -0,0 +1,45 @@
+package key
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file|-]",
+	Short: "Import an identity key",
+	Long: "Import an ed25519 identity key from a file, or from stdin if the file " +
+		"is \"-\" or not given, and write it as a PKCS#8 PEM to the output file, or " +
+		"to stdout if no output file is given. The input may be a PEM, a multibase " +
+		"encoded key or a mnemonic, and is detected if no format is given.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: doImport,
+}
+
+func init() {
+	importCmd.Flags().String("format", "", fmt.Sprintf("Format of the input key, one of %q", formats))
+	addWriteFlags(importCmd)
+}
+
+func doImport(cmd *cobra.Command, args []string) error {
+	var (
+		input []byte
+		err   error
+	)
+	if len(args) == 0 || args[0] == "-" {
+		input, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		input, err = os.ReadFile(args[0])
+	}
+	cobra.CheckErr(err)
+
+	format, _ := cmd.Flags().GetString("format")
+	signer, err := decodeKey(input, format)
+	if err != nil {
+		return fmt.Errorf("decoding key: %w", err)
+	}
+	return writeKey(cmd, signer)
+}
@@ -0,0 +1,17 @@
+package key
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "key",
+	Short: "Generate, show and convert identity keys",
+}
+
+func init() {
+	Cmd.AddCommand(generateCmd)
+	Cmd.AddCommand(didCmd)
+	Cmd.AddCommand(exportCmd)
+	Cmd.AddCommand(importCmd)
+}
@@ -0,0 +1,291 @@
+package locate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/alanshaw/buff/pkg/blobindex"
+	assert_caps "github.com/alanshaw/buff/pkg/capabilities/assert"
+	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/buff/pkg/indexer"
+	"github.com/alanshaw/buff/pkg/principal"
+	lib_assert_caps "github.com/alanshaw/libracha/capabilities/assert"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/validator"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/multiformats/go-multihash"
+	"github.com/spf13/cobra"
+)
+
+var log = logging.Logger("cmd/locate")
+
+var Cmd = &cobra.Command{
+	Use:   "locate <cid|multihash>",
+	Short: "Find the locations of a blob or block",
+	Long: "Query the indexing service for location commitments and index claims " +
+		"for a CID or base58btc encoded multihash. Claim signatures are verified, " +
+		"index claims must be issued by the indexing service (services.indexer.id) " +
+		"and claims must be about the queried content. When the queried content " +
+		"is a block in a DAG, location commitments for the shards that the " +
+		"index names as holding the block are also accepted. Claims that fail " +
+		"verification are reported and ignored.",
+	Args: cobra.ExactArgs(1),
+	RunE: cli.FXCommand(doLocate),
+}
+
+func init() {
+	Cmd.Flags().StringSlice("space", nil, "Only return claims for content in this space (may be repeated)")
+}
+
+func doLocate(cmd *cobra.Command, args []string, serviceConfig app.ExternalServicesConfig) error {
+	digest, err := ParseDigest(args[0])
+	cobra.CheckErr(err)
+
+	spaceStrs, err := cmd.Flags().GetStringSlice("space")
+	cobra.CheckErr(err)
+	var spaces []did.DID
+	for _, s := range spaceStrs {
+		space, err := did.Parse(s)
+		cobra.CheckErr(err)
+		spaces = append(spaces, space)
+	}
+
+	client := indexer.New(serviceConfig.Indexer.URL)
+	result, err := client.Query(cmd.Context(), digest, indexer.WithSpaces(spaces...))
+	if err != nil {
+		return fmt.Errorf("querying indexing service: %w", err)
+	}
+
+	// claims are verified before any are printed, because a location
+	// commitment for a shard is only accepted if a valid index claim names the
+	// shard
+	resolver := principal.NewResolver(nil)
+	var locClaims []locationClaim
+	var idxClaims []indexClaim
+	for _, claim := range result.Invocations() {
+		switch claim.Command() {
+		case lib_assert_caps.LocationCommand:
+			loc := lib_assert_caps.LocationArguments{}
+			if err := datamodel.Rebind(datamodel.NewAny(claim.Arguments()), &loc); err != nil {
+				log.Warnf("decoding location commitment %s: %s", claim.Link(), err)
+				continue
+			}
+			if err := verifyClaim(cmd, resolver, claim); err != nil {
+				cmd.Printf("❌ location commitment %s failed verification: %s\n", claim.Link(), err)
+				continue
+			}
+			locClaims = append(locClaims, locationClaim{claim: claim, args: loc})
+		case assert_caps.IndexCommand:
+			idx := assert_caps.IndexArguments{}
+			if err := datamodel.Rebind(datamodel.NewAny(claim.Arguments()), &idx); err != nil {
+				log.Warnf("decoding index claim %s: %s", claim.Link(), err)
+				continue
+			}
+			if claim.Issuer().DID() != serviceConfig.Indexer.ID {
+				cmd.Printf("❌ index claim %s was not issued by the indexing service: %s\n", claim.Link(), claim.Issuer().DID())
+				continue
+			}
+			if err := verifyClaim(cmd, resolver, claim); err != nil {
+				cmd.Printf("❌ index claim %s failed verification: %s\n", claim.Link(), err)
+				continue
+			}
+			idxClaims = append(idxClaims, indexClaim{claim: claim, args: idx})
+		default:
+			log.Debugf("ignoring %q claim: %s", claim.Command(), claim.Link())
+		}
+	}
+
+	// shards holds the digests of the shards that contain the queried content,
+	// according to the accepted index claims, and indexBlobs the digests of
+	// the indexes themselves
+	shards := map[string]bool{}
+	indexBlobs := map[string]bool{}
+	indexes := 0
+	for _, ic := range idxClaims {
+		// the index is needed to find the shards holding the content, but an
+		// index claim for the queried content is valid without it
+		var holders []multihash.Multihash
+		index, err := fetchIndex(cmd, ic.args.Index.Hash(), locClaims)
+		if err == nil && index.Content.String() != ic.args.Content.String() {
+			err = fmt.Errorf("index is for different content: %s", index.Content)
+		}
+		if err == nil {
+			holders = index.ShardsContaining(digest)
+		}
+		if !bytes.Equal(ic.args.Content.Hash(), digest) {
+			if err != nil {
+				cmd.Printf("❌ index claim %s could not be checked: %s\n", ic.claim.Link(), err)
+				continue
+			}
+			if len(holders) == 0 {
+				cmd.Printf("❌ index claim %s is for different content: %s\n", ic.claim.Link(), ic.args.Content)
+				continue
+			}
+		} else if err != nil {
+			log.Warnf("fetching index %s: %s", ic.args.Index, err)
+		}
+		for _, s := range holders {
+			shards[string(s)] = true
+		}
+		indexBlobs[string(ic.args.Index.Hash())] = true
+		indexes++
+		cmd.Printf("🗂️ index claim: %s\n", ic.claim.Link())
+		cmd.Printf("   issuer: %s\n", ic.claim.Issuer().DID())
+		cmd.Printf("   content: %s\n", ic.args.Content)
+		cmd.Printf("   index: %s\n", ic.args.Index)
+	}
+
+	locations := 0
+	for _, lc := range locClaims {
+		switch {
+		case bytes.Equal(lc.args.Content, digest):
+			locations++
+			printLocation(cmd, "📍 location commitment", lc)
+		case shards[string(lc.args.Content)]:
+			locations++
+			printLocation(cmd, "📦 shard location commitment", lc)
+		case indexBlobs[string(lc.args.Content)]:
+			printLocation(cmd, "🗂️ index location commitment", lc)
+		default:
+			cmd.Printf("❌ location commitment %s is for different content: %s\n", lc.claim.Link(), digestutil.Format(lc.args.Content))
+		}
+	}
+
+	if locations == 0 && indexes == 0 {
+		return fmt.Errorf("no claims found for: %s", digestutil.Format(digest))
+	}
+	return nil
+}
+
+type locationClaim struct {
+	claim ucan.Invocation
+	args  lib_assert_caps.LocationArguments
+}
+
+type indexClaim struct {
+	claim ucan.Invocation
+	args  assert_caps.IndexArguments
+}
+
+func printLocation(cmd *cobra.Command, label string, lc locationClaim) {
+	cmd.Printf("%s: %s\n", label, lc.claim.Link())
+	cmd.Printf("   provider: %s\n", lc.claim.Issuer().DID())
+	cmd.Printf("   space: %s\n", lc.args.Space)
+	cmd.Printf("   content: %s\n", digestutil.Format(lc.args.Content))
+	for _, u := range lc.args.Location {
+		cmd.Printf("   url: %s\n", u.URL().String())
+	}
+	if lc.args.Range != nil {
+		if lc.args.Range.Length != nil {
+			cmd.Printf("   range: %d-%d\n", lc.args.Range.Offset, lc.args.Range.Offset+*lc.args.Range.Length-1)
+		} else {
+			cmd.Printf("   range: %d-\n", lc.args.Range.Offset)
+		}
+	}
+	if exp := lc.claim.Expiration(); exp != nil {
+		cmd.Printf("   expires: %d\n", *exp)
+	}
+}
+
+// maxIndexSize is the maximum size of an index blob that will be fetched.
+const maxIndexSize = 64 << 20
+
+// fetchIndex fetches the index blob with the passed digest from the locations
+// in the verified location commitments, verifies it against the digest and
+// extracts it.
+func fetchIndex(cmd *cobra.Command, digest multihash.Multihash, locClaims []locationClaim) (*blobindex.ShardedDAGIndex, error) {
+	var errs []error
+	for _, lc := range locClaims {
+		if !bytes.Equal(lc.args.Content, digest) {
+			continue
+		}
+		for _, u := range lc.args.Location {
+			data, err := fetchBlob(cmd, u.URL().String(), lc.args.Range, digest)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			return blobindex.Extract(bytes.NewReader(data))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no locations found for index: %s", digestutil.Format(digest))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func fetchBlob(cmd *cobra.Command, url string, rng *lib_assert_caps.Range, digest multihash.Multihash) ([]byte, error) {
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if rng != nil {
+		if rng.Length != nil {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rng.Offset, rng.Offset+*rng.Length-1))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", rng.Offset))
+		}
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("fetching %s: unexpected status: %s", url, res.Status)
+	}
+	if rng != nil && res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("fetching %s: range request not supported", url)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxIndexSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	if len(data) > maxIndexSize {
+		return nil, fmt.Errorf("fetching %s: index exceeds %d bytes", url, maxIndexSize)
+	}
+	dmh, err := multihash.Decode(digest)
+	if err != nil {
+		return nil, fmt.Errorf("decoding digest: %w", err)
+	}
+	actual, err := multihash.Sum(data, dmh.Code, dmh.Length)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(actual, digest) {
+		return nil, fmt.Errorf("hash mismatch for index fetched from %s", url)
+	}
+	return data, nil
+}
+
+func verifyClaim(cmd *cobra.Command, resolver *principal.Resolver, claim ucan.Invocation) error {
+	verifier, err := resolver.ResolveVerifier(cmd.Context(), claim.Issuer().DID())
+	if err != nil {
+		return fmt.Errorf("resolving issuer key: %w", err)
+	}
+	if err := validator.VerifyInvocationSignature(claim, verifier); err != nil {
+		return err
+	}
+	return validator.ValidateNotExpired(claim)
+}
+
+// ParseDigest parses a CID or multibase encoded multihash, returning the
+// multihash.
+func ParseDigest(s string) (multihash.Multihash, error) {
+	if c, err := cid.Decode(s); err == nil {
+		return c.Hash(), nil
+	}
+	digest, err := digestutil.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CID or multihash: %w", err)
+	}
+	return digest, nil
+}
@@ -0,0 +1,139 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alanshaw/buff/pkg/config"
+	"github.com/alanshaw/buff/pkg/config/lib"
+	"github.com/alanshaw/buff/pkg/presets"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/spf13/cobra"
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a config profile",
+	Long: "Add a profile to the config file. A new identity key is generated for " +
+		"the profile unless --key-file is given, and the profile has its own data " +
+		"directory unless --data-dir is given. Service settings for the profile " +
+		"may be added to the config file under [profiles.<name>.services]. With " +
+		"--force, the network, key file and data directory of an existing profile " +
+		"are updated, leaving its other settings as they are, and a key previously " +
+		"generated for the profile is kept. Profile names are case-insensitive and " +
+		"are stored in lower case.",
+	Args: cobra.ExactArgs(1),
+	RunE: doAdd,
+}
+
+func init() {
+	addCmd.Flags().String("network", string(presets.Dev), fmt.Sprintf("Network to use, one of %q", presets.AvailableNetworks))
+	addCmd.Flags().Bool("encrypt", false, "Encrypt the generated identity key with a passphrase, read from BUFF_PASSPHRASE or the terminal")
+	addCmd.Flags().Bool("force", false, "Update an existing profile, keeping its generated identity key")
+}
+
+func doAdd(cmd *cobra.Command, args []string) error {
+	// keys in the config file are case-insensitive
+	name := strings.ToLower(args[0])
+	if name == config.DefaultProfile {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	force, _ := cmd.Flags().GetBool("force")
+	if slices.Contains(config.Profiles(), name) && !force {
+		return fmt.Errorf("profile already exists: %q, use --force to update it", name)
+	}
+	networkStr, _ := cmd.Flags().GetString("network")
+	network, err := presets.ParseNetwork(networkStr)
+	if err != nil {
+		return err
+	}
+
+	v, err := readConfigFile()
+	cobra.CheckErr(err)
+
+	var id principal.Signer
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	if cmd.Flags().Changed("key-file") {
+		id, err = lib.SignerFromEd25519PEMFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("reading key file: %w", err)
+		}
+	} else {
+		keyFile = filepath.Join(filepath.Dir(v.ConfigFileUsed()), fmt.Sprintf("identity-%s.pem", name))
+		id, err = profileKey(cmd, keyFile, force)
+		if err != nil {
+			return err
+		}
+	}
+	keyFile, err = filepath.Abs(keyFile)
+	cobra.CheckErr(err)
+
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	if !cmd.Flags().Changed("data-dir") {
+		home, err := os.UserHomeDir()
+		cobra.CheckErr(err)
+		dataDir = filepath.Join(home, ".buff", "profiles", name)
+	}
+	dataDir, err = filepath.Abs(dataDir)
+	cobra.CheckErr(err)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+
+	// settings of an existing profile other than those set here are kept
+	v.Set(fmt.Sprintf("profiles.%s.network", name), network.String())
+	v.Set(fmt.Sprintf("profiles.%s.identity.key_file", name), keyFile)
+	v.Set(fmt.Sprintf("profiles.%s.repo.data_dir", name), dataDir)
+	if err := v.WriteConfig(); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+
+	cmd.Printf("👤 added profile %q, use it with \"buff profile use %s\"\n", name, name)
+	cmd.Println("")
+	cmd.Println("Agent DID:")
+	cmd.Println(id.DID())
+	return nil
+}
+
+// profileKey generates the identity key for a profile and writes it to the key
+// file. If the key file exists, because the profile was added before, the key
+// in it is used instead, but only if force is true.
+func profileKey(cmd *cobra.Command, keyFile string, force bool) (principal.Signer, error) {
+	if _, err := os.Stat(keyFile); err == nil {
+		if !force {
+			return nil, fmt.Errorf("key file already exists: %s, use --force to add the profile with this key", keyFile)
+		}
+		id, err := lib.SignerFromEd25519PEMFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading existing key file: %w", err)
+		}
+		cmd.Printf("🔑 using existing identity key: %s\n", keyFile)
+		return id, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var passphrase []byte
+	if encrypt, _ := cmd.Flags().GetBool("encrypt"); encrypt {
+		p, err := lib.NewPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		passphrase = p
+	}
+	id, err := ed25519.Generate()
+	if err != nil {
+		return nil, err
+	}
+	if err := lib.WriteEd25519PEMFile(keyFile, id, passphrase, false); err != nil {
+		return nil, err
+	}
+	cmd.Printf("🔑 generated identity key: %s\n", keyFile)
+	return id, nil
+}
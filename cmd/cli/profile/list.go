@@ -0,0 +1,27 @@
+package profile
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List config profiles",
+	Args:    cobra.NoArgs,
+	RunE:    doList,
+}
+
+func doList(cmd *cobra.Command, args []string) error {
+	for _, name := range config.Profiles() {
+		marker := " "
+		if name == current() {
+			marker = "*"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", marker, name)
+	}
+	return nil
+}
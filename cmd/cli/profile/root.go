@@ -0,0 +1,50 @@
+package profile
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/alanshaw/buff/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage config profiles",
+	Long: "Profiles are named sets of settings in the config file, under the " +
+		"\"profiles\" table, e.g. [profiles.team.identity]. The settings of the " +
+		"current profile are used in place of the top level settings, which make " +
+		"up the \"" + config.DefaultProfile + "\" profile. The current profile can " +
+		"be overridden with --profile or BUFF_PROFILE.",
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(useCmd)
+	Cmd.AddCommand(addCmd)
+}
+
+// readConfigFile reads the config file in use, without the settings from
+// flags, environment variables or profiles, so that it can be modified and
+// written back.
+func readConfigFile() (*viper.Viper, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return nil, errors.New("no config file found, run \"buff init\" to create one")
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// current returns the name of the profile in use.
+func current() string {
+	if name := viper.GetString("profile"); name != "" {
+		return strings.ToLower(name)
+	}
+	return config.DefaultProfile
+}
@@ -0,0 +1,39 @@
+package profile
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alanshaw/buff/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the current config profile",
+	Long:  "Set the current config profile. Profile names are case-insensitive.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  doUse,
+}
+
+func doUse(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+	if !slices.Contains(config.Profiles(), name) {
+		return fmt.Errorf("unknown profile: %q (available profiles are: %q)", name, config.Profiles())
+	}
+
+	v, err := readConfigFile()
+	cobra.CheckErr(err)
+	if name == config.DefaultProfile {
+		v.Set("profile", "")
+	} else {
+		v.Set("profile", name)
+	}
+	if err := v.WriteConfig(); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+
+	cmd.Printf("👤 using profile %q\n", name)
+	return nil
+}
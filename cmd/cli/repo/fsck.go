@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/spf13/cobra"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check the delegation store for inconsistencies",
+	Long: "Check every delegation is stored under its link key and all of its " +
+		"index keys, and that every index key refers to a stored delegation and " +
+		"holds the same bytes as its link key. A corrupt delegation is restored " +
+		"from an intact index key when one exists. " +
+		"Inconsistencies may be left behind if buff was interrupted while writing " +
+		"to the store by an earlier version.",
+	Args: cobra.NoArgs,
+	RunE: cli.FXCommand(doFsck),
+}
+
+func init() {
+	fsckCmd.Flags().Bool("repair", false, "Repair any inconsistencies found")
+}
+
+func doFsck(cmd *cobra.Command, delegationStore dlgstore.Store) error {
+	repair, _ := cmd.Flags().GetBool("repair")
+	report, err := delegationStore.Fsck(cmd.Context(), repair)
+	cobra.CheckErr(err)
+
+	cmd.Printf("🔍 checked %d delegation(s)\n", report.Delegations)
+	for _, k := range report.Corrupt {
+		cmd.Printf("💥 corrupt delegation: %s\n", k)
+	}
+	for _, k := range report.Restored {
+		cmd.Printf("🩹 corrupt or missing delegation with an intact index copy: %s\n", k)
+	}
+	for _, k := range report.Missing {
+		cmd.Printf("❓ missing index key: %s\n", k)
+	}
+	for _, k := range report.Dangling {
+		cmd.Printf("🔗 dangling index key: %s\n", k)
+	}
+	for _, k := range report.Stale {
+		cmd.Printf("🕸️ stale index key: %s\n", k)
+	}
+
+	if report.OK() {
+		cmd.Println("✅ no inconsistencies found")
+		return nil
+	}
+	n := len(report.Corrupt) + len(report.Restored) + len(report.Missing) + len(report.Dangling) + len(report.Stale)
+	if !repair {
+		return fmt.Errorf("found %d inconsistencies, run with --repair to fix them", n)
+	}
+	cmd.Printf("🔧 repaired %d inconsistencies\n", n)
+	return nil
+}
@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "repo",
+	Short: "Manage the local data repository",
+}
+
+func init() {
+	Cmd.AddCommand(fsckCmd)
+}
@@ -0,0 +1,321 @@
+package retrieve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/alanshaw/buff/pkg/blobindex"
+	assert_caps "github.com/alanshaw/buff/pkg/capabilities/assert"
+	"github.com/alanshaw/buff/pkg/indexer"
+	"github.com/alanshaw/buff/pkg/principal"
+	lib_assert_caps "github.com/alanshaw/libracha/capabilities/assert"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/validator"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// maxIndexSize is the maximum size of an index blob that will be fetched.
+const maxIndexSize = 64 << 20
+
+type location struct {
+	url    *url.URL
+	offset uint64
+	length *uint64
+}
+
+// fetcher resolves the locations of blobs using the indexing service and
+// fetches data from them over HTTP, verifying everything it fetches.
+type fetcher struct {
+	ctx      context.Context
+	indexer  *indexer.Client
+	client   *http.Client
+	resolver *principal.Resolver
+	spaces   []did.DID
+	// locations of blobs, keyed by digest
+	locations map[string][]location
+	// indexes of DAGs, keyed by content root digest
+	indexes map[string][]ucan.Link
+	// queried records digests the indexer has already been asked about
+	queried map[string]bool
+	// blocks maps block digests to their position within a shard
+	blocks map[string]blockPosition
+}
+
+type blockPosition struct {
+	shard    multihash.Multihash
+	position blobindex.Position
+}
+
+func newFetcher(ctx context.Context, client *indexer.Client, spaces []did.DID) *fetcher {
+	return &fetcher{
+		ctx:       ctx,
+		indexer:   client,
+		client:    http.DefaultClient,
+		resolver:  principal.NewResolver(http.DefaultClient),
+		spaces:    spaces,
+		locations: map[string][]location{},
+		indexes:   map[string][]ucan.Link{},
+		queried:   map[string]bool{},
+		blocks:    map[string]blockPosition{},
+	}
+}
+
+// query asks the indexing service for claims about the digest, caching any
+// valid location commitments and index claims that are returned.
+func (f *fetcher) query(digest multihash.Multihash) error {
+	if f.queried[string(digest)] {
+		return nil
+	}
+	result, err := f.indexer.Query(f.ctx, digest, indexer.WithSpaces(f.spaces...))
+	if err != nil {
+		return fmt.Errorf("querying indexing service for %s: %w", digestutil.Format(digest), err)
+	}
+	f.queried[string(digest)] = true
+
+	for _, claim := range result.Invocations() {
+		switch claim.Command() {
+		case lib_assert_caps.LocationCommand:
+			loc := lib_assert_caps.LocationArguments{}
+			if err := datamodel.Rebind(datamodel.NewAny(claim.Arguments()), &loc); err != nil {
+				log.Warnf("decoding location commitment %s: %s", claim.Link(), err)
+				continue
+			}
+			if err := f.verifyClaim(claim); err != nil {
+				log.Warnf("location commitment %s failed verification: %s", claim.Link(), err)
+				continue
+			}
+			for _, u := range loc.Location {
+				l := location{url: u.URL()}
+				if loc.Range != nil {
+					l.offset = loc.Range.Offset
+					l.length = loc.Range.Length
+				}
+				f.locations[string(loc.Content)] = append(f.locations[string(loc.Content)], l)
+			}
+		case assert_caps.IndexCommand:
+			idx := assert_caps.IndexArguments{}
+			if err := datamodel.Rebind(datamodel.NewAny(claim.Arguments()), &idx); err != nil {
+				log.Warnf("decoding index claim %s: %s", claim.Link(), err)
+				continue
+			}
+			if err := f.verifyClaim(claim); err != nil {
+				log.Warnf("index claim %s failed verification: %s", claim.Link(), err)
+				continue
+			}
+			f.indexes[string(idx.Content.Hash())] = append(f.indexes[string(idx.Content.Hash())], idx.Index)
+		}
+	}
+	return nil
+}
+
+func (f *fetcher) verifyClaim(claim ucan.Invocation) error {
+	verifier, err := f.resolver.ResolveVerifier(f.ctx, claim.Issuer().DID())
+	if err != nil {
+		return fmt.Errorf("resolving issuer key: %w", err)
+	}
+	if err := validator.VerifyInvocationSignature(claim, verifier); err != nil {
+		return err
+	}
+	return validator.ValidateNotExpired(claim)
+}
+
+// locate returns the known locations of the blob with the passed digest.
+func (f *fetcher) locate(digest multihash.Multihash) ([]location, error) {
+	if err := f.query(digest); err != nil {
+		return nil, err
+	}
+	locs := f.locations[string(digest)]
+	if len(locs) == 0 {
+		return nil, fmt.Errorf("no locations found for blob: %s", digestutil.Format(digest))
+	}
+	return locs, nil
+}
+
+// get requests a byte range of the blob at the location. A nil length requests
+// the remainder of the blob. An empty range cannot be expressed in a Range
+// header, so no request is made for it and the returned reader is empty.
+func (f *fetcher) get(loc location, offset uint64, length *uint64) (io.ReadCloser, error) {
+	if (length != nil && *length == 0) || (length == nil && loc.length != nil && offset >= *loc.length) {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, loc.url.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	start := loc.offset + offset
+	ranged := start > 0 || length != nil || loc.length != nil
+	if ranged {
+		end := ""
+		if length != nil {
+			end = fmt.Sprint(start + *length - 1)
+		} else if loc.length != nil {
+			end = fmt.Sprint(loc.offset + *loc.length - 1)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%s", start, end))
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", loc.url, err)
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching %s: unexpected status: %s", loc.url, res.Status)
+	}
+	if ranged && res.StatusCode != http.StatusPartialContent {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching %s: range request not supported", loc.url)
+	}
+	return res.Body, nil
+}
+
+// fetchBlob streams the entire blob to w, verifying it against the digest.
+// An error is returned if the data does not match, so w should not be trusted
+// until fetchBlob returns successfully.
+func (f *fetcher) fetchBlob(digest multihash.Multihash, w io.Writer) error {
+	locs, err := f.locate(digest)
+	if err != nil {
+		return err
+	}
+	dmh, err := multihash.Decode(digest)
+	if err != nil {
+		return fmt.Errorf("decoding digest: %w", err)
+	}
+	var errs []error
+	for _, loc := range locs {
+		body, err := f.get(loc, 0, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		hasher, err := multihash.GetHasher(dmh.Code)
+		if err != nil {
+			body.Close()
+			return err
+		}
+		_, err = io.Copy(io.MultiWriter(w, hasher), body)
+		body.Close()
+		if err != nil {
+			// data may already have been written, so do not try other locations
+			return fmt.Errorf("reading blob from %s: %w", loc.url, err)
+		}
+		actual, err := multihash.Encode(hasher.Sum(nil)[:dmh.Length], dmh.Code)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(actual, digest) {
+			return fmt.Errorf("hash mismatch for blob fetched from %s: expected %s, got %s", loc.url, digestutil.Format(digest), digestutil.Format(actual))
+		}
+		return nil
+	}
+	return fmt.Errorf("fetching blob %s: %w", digestutil.Format(digest), errors.Join(errs...))
+}
+
+// loadIndex finds, fetches and verifies the index for the DAG with the passed
+// root, recording the positions of its blocks.
+func (f *fetcher) loadIndex(root cid.Cid) error {
+	if err := f.query(root.Hash()); err != nil {
+		return err
+	}
+	links := f.indexes[string(root.Hash())]
+	if len(links) == 0 {
+		return fmt.Errorf("no index found for: %s", root)
+	}
+	var errs []error
+	for _, l := range links {
+		var buf bytes.Buffer
+		if err := f.fetchBlob(l.Hash(), &limitWriter{w: &buf, n: maxIndexSize}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		idx, err := blobindex.Extract(&buf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("extracting index %s: %w", l, err))
+			continue
+		}
+		if idx.Content != root {
+			errs = append(errs, fmt.Errorf("index %s is for %s not %s", l, idx.Content, root))
+			continue
+		}
+		for _, s := range idx.Shards {
+			for _, slc := range s.Slices {
+				f.blocks[string(slc.Digest)] = blockPosition{shard: s.Digest, position: slc.Position}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("loading index for %s: %w", root, errors.Join(errs...))
+}
+
+// getBlock fetches a block of the DAG and verifies it against its CID.
+func (f *fetcher) getBlock(c cid.Cid) ([]byte, error) {
+	pos, ok := f.blocks[string(c.Hash())]
+	if !ok {
+		return nil, fmt.Errorf("block not found in index: %s", c)
+	}
+	if pos.position.Length == 0 {
+		// an empty block, such as an empty file, has nothing to fetch
+		b := []byte{}
+		if err := verifyBlock(c, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	locs, err := f.locate(pos.shard)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, loc := range locs {
+		body, err := f.get(loc, pos.position.Offset, &pos.position.Length)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		b, err := io.ReadAll(io.LimitReader(body, int64(pos.position.Length)))
+		body.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := verifyBlock(c, b); err != nil {
+			errs = append(errs, fmt.Errorf("block fetched from %s: %w", loc.url, err))
+			continue
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("fetching block %s: %w", c, errors.Join(errs...))
+}
+
+func verifyBlock(c cid.Cid, b []byte) error {
+	prefix := c.Prefix()
+	digest, err := multihash.Sum(b, prefix.MhType, prefix.MhLength)
+	if err != nil {
+		return fmt.Errorf("hashing block: %w", err)
+	}
+	if !bytes.Equal(digest, c.Hash()) {
+		return fmt.Errorf("hash mismatch for block %s", c)
+	}
+	return nil
+}
+
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.n {
+		return 0, errors.New("size limit exceeded")
+	}
+	lw.n -= int64(len(p))
+	return lw.w.Write(p)
+}
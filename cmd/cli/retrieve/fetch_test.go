@@ -0,0 +1,84 @@
+package retrieve
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/blobindex"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// newTestFetcher creates a fetcher that knows the blob is at a location on a
+// server that fails the test if it receives a request.
+func newTestFetcher(t *testing.T, blob multihash.Multihash, length *uint64) *fetcher {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %s (range %q)", r.URL, r.Header.Get("Range"))
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL + "/blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFetcher(context.Background(), nil, nil)
+	f.queried[string(blob)] = true
+	f.locations[string(blob)] = []location{{url: u, length: length}}
+	return f
+}
+
+func TestFetchEmptyBlob(t *testing.T) {
+	digest, err := multihash.Sum(nil, multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	length := uint64(0)
+	f := newTestFetcher(t, digest, &length)
+
+	var buf bytes.Buffer
+	if err := f.fetchBlob(digest, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("fetched %d bytes, expected none", buf.Len())
+	}
+}
+
+func TestFetchEmptyFile(t *testing.T) {
+	empty, err := multihash.Sum(nil, multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard, err := multihash.Sum([]byte("shard"), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newTestFetcher(t, shard, nil)
+	f.blocks[string(empty)] = blockPosition{shard: shard, position: blobindex.Position{Offset: 42, Length: 0}}
+
+	root := cid.NewCidV1(cid.Raw, empty)
+	blk, err := f.getBlock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := exportFile(f, root, blk, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("exported %d bytes, expected none", buf.Len())
+	}
+
+	// a block that is not empty must not be accepted for an empty range
+	other := cid.NewCidV1(cid.Raw, shard)
+	f.blocks[string(shard)] = blockPosition{shard: shard, position: blobindex.Position{Offset: 0, Length: 0}}
+	if _, err := f.getBlock(other); err == nil {
+		t.Fatal("expected an empty range to fail verification for a non-empty block")
+	}
+}
@@ -0,0 +1,314 @@
+package retrieve
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alanshaw/buff/cmd/cli/locate"
+	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	"github.com/alanshaw/buff/pkg/indexer"
+	"github.com/alanshaw/buff/pkg/unixfs"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/spf13/cobra"
+)
+
+var log = logging.Logger("cmd/retrieve")
+
+var Cmd = &cobra.Command{
+	Use:     "retrieve <cid>",
+	Aliases: []string{"get"},
+	Short:   "Retrieve content from the Storacha Network",
+	Long: "Retrieve content from the Storacha Network. Locations are resolved " +
+		"using the indexing service and all data is verified against its hash " +
+		"before it is written. Raw CIDs (or multihashes) are fetched as a single " +
+		"blob, UnixFS roots are reassembled into files and directories. " +
+		"Directories are written to a temporary directory that is moved into " +
+		"place once everything has been verified. Symlinks in directories are " +
+		"skipped unless --symlinks is given.",
+	Args: cobra.ExactArgs(1),
+	RunE: cli.FXCommand(doRetrieve),
+}
+
+func init() {
+	Cmd.Flags().StringP("output", "o", "", "Path to write the retrieved file or directory to. Files are written to stdout if not set, directories to a directory named after the CID.")
+	Cmd.Flags().StringSlice("space", nil, "Only use claims for content in this space (may be repeated)")
+	Cmd.Flags().Bool("symlinks", false, "Create symlinks found in directories. Absolute targets and targets containing \"..\" are rejected.")
+}
+
+func doRetrieve(cmd *cobra.Command, args []string, serviceConfig app.ExternalServicesConfig) error {
+	output, err := cmd.Flags().GetString("output")
+	cobra.CheckErr(err)
+	symlinks, err := cmd.Flags().GetBool("symlinks")
+	cobra.CheckErr(err)
+
+	spaceStrs, err := cmd.Flags().GetStringSlice("space")
+	cobra.CheckErr(err)
+	var spaces []did.DID
+	for _, s := range spaceStrs {
+		space, err := did.Parse(s)
+		cobra.CheckErr(err)
+		spaces = append(spaces, space)
+	}
+
+	f := newFetcher(cmd.Context(), indexer.New(serviceConfig.Indexer.URL), spaces)
+
+	root, err := cid.Decode(args[0])
+	if err != nil {
+		// not a CID, may be a multihash of a blob
+		digest, err := locate.ParseDigest(args[0])
+		cobra.CheckErr(err)
+		root = cid.NewCidV1(cid.Raw, digest)
+	}
+
+	if root.Type() == cid.Raw {
+		locs, err := f.locate(root.Hash())
+		if err == nil && len(locs) > 0 {
+			return writeVerified(cmd, output, func(w io.Writer) error {
+				return f.fetchBlob(root.Hash(), w)
+			})
+		}
+		log.Debugf("no location for raw CID %s, looking for an index: %s", root, err)
+	}
+
+	if err := f.loadIndex(root); err != nil {
+		return err
+	}
+
+	blk, err := f.getBlock(root)
+	if err != nil {
+		return err
+	}
+	if root.Type() == cid.DagProtobuf {
+		node, err := unixfs.DecodeNode(blk)
+		if err != nil {
+			return fmt.Errorf("decoding root node: %w", err)
+		}
+		data, err := unixfs.DecodeData(node.Data)
+		if err != nil {
+			return fmt.Errorf("decoding root node UnixFS data: %w", err)
+		}
+		if data.Type == unixfs.TDirectory {
+			if output == "" {
+				output = root.String()
+			}
+			if err := exportDirectoryVerified(f, node, output, symlinks); err != nil {
+				return err
+			}
+			cmd.PrintErrf("✅ retrieved %s to %s\n", root, output)
+			return nil
+		}
+	}
+
+	return writeVerified(cmd, output, func(w io.Writer) error {
+		return exportFile(f, root, blk, w)
+	})
+}
+
+// writeVerified writes data to the output path, or stdout if not set. When
+// writing to a path, data is written to a temporary file that is moved into
+// place only once write returns successfully. When writing to stdout data
+// that is not verified incrementally is spooled to a temporary file first.
+func writeVerified(cmd *cobra.Command, output string, write func(w io.Writer) error) error {
+	dir := os.TempDir()
+	if output != "" {
+		dir = filepath.Dir(output)
+	}
+	tmp, err := os.CreateTemp(dir, ".buff-retrieve-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+
+	if output != "" {
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), output); err != nil {
+			return fmt.Errorf("moving retrieved data into place: %w", err)
+		}
+		cmd.PrintErrf("✅ retrieved to %s\n", output)
+		return nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(cmd.OutOrStdout(), tmp)
+	return err
+}
+
+// exportFile writes the contents of the UnixFS file with the passed root block
+// to w. Every block is verified before its data is written.
+func exportFile(f *fetcher, c cid.Cid, blk []byte, w io.Writer) error {
+	switch c.Type() {
+	case cid.Raw:
+		_, err := w.Write(blk)
+		return err
+	case cid.DagProtobuf:
+		node, err := unixfs.DecodeNode(blk)
+		if err != nil {
+			return fmt.Errorf("decoding node %s: %w", c, err)
+		}
+		data, err := unixfs.DecodeData(node.Data)
+		if err != nil {
+			return fmt.Errorf("decoding UnixFS data for %s: %w", c, err)
+		}
+		if data.Type != unixfs.TFile && data.Type != unixfs.TRaw {
+			return fmt.Errorf("node %s is not a file (type %d)", c, data.Type)
+		}
+		if len(data.Data) > 0 {
+			if _, err := w.Write(data.Data); err != nil {
+				return err
+			}
+		}
+		for _, l := range node.Links {
+			child, err := f.getBlock(l.Cid)
+			if err != nil {
+				return err
+			}
+			if err := exportFile(f, l.Cid, child, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported codec 0x%x for %s", c.Type(), c)
+	}
+}
+
+// exportDirectoryVerified recreates the UnixFS directory at output. The
+// directory is exported to a temporary directory that is moved into place only
+// once the export completes, so that nothing is left at output if any data
+// fails verification.
+func exportDirectoryVerified(f *fetcher, node unixfs.Node, output string, symlinks bool) error {
+	if _, err := os.Lstat(output); err == nil {
+		return fmt.Errorf("output already exists: %s", output)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(output), ".buff-retrieve-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// the temporary directory is the root, exportDirectory creates the children
+	if err := exportDirectory(f, node, tmp, symlinks); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		return fmt.Errorf("moving retrieved directory into place: %w", err)
+	}
+	return nil
+}
+
+// exportDirectory recreates the entries of the UnixFS directory in the existing
+// directory at path.
+func exportDirectory(f *fetcher, node unixfs.Node, path string, symlinks bool) error {
+	seen := map[string]struct{}{}
+	for _, l := range node.Links {
+		if err := validateName(l.Name); err != nil {
+			return err
+		}
+		// a duplicate name could otherwise be used to write through a symlink
+		// created by an earlier entry
+		if _, ok := seen[l.Name]; ok {
+			return fmt.Errorf("duplicate directory entry name: %q", l.Name)
+		}
+		seen[l.Name] = struct{}{}
+		p := filepath.Join(path, l.Name)
+		blk, err := f.getBlock(l.Cid)
+		if err != nil {
+			return err
+		}
+		if l.Cid.Type() == cid.DagProtobuf {
+			child, err := unixfs.DecodeNode(blk)
+			if err != nil {
+				return fmt.Errorf("decoding node %s: %w", l.Cid, err)
+			}
+			data, err := unixfs.DecodeData(child.Data)
+			if err != nil {
+				return fmt.Errorf("decoding UnixFS data for %s: %w", l.Cid, err)
+			}
+			switch data.Type {
+			case unixfs.TDirectory:
+				if err := os.Mkdir(p, 0755); err != nil {
+					return err
+				}
+				if err := exportDirectory(f, child, p, symlinks); err != nil {
+					return err
+				}
+				continue
+			case unixfs.THAMTShard:
+				return fmt.Errorf("sharded directories are not supported: %s", l.Cid)
+			case unixfs.TSymlink:
+				target := string(data.Data)
+				if !symlinks {
+					log.Warnf("skipping symlink %s -> %s", p, target)
+					continue
+				}
+				if err := validateSymlink(target); err != nil {
+					return err
+				}
+				if err := os.Symlink(target, p); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		err = writeFile(p, func(w io.Writer) error {
+			return exportFile(f, l.Cid, blk, w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := write(out); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
+
+// validateName ensures a directory entry name cannot escape the directory.
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid directory entry name: %q", name)
+	}
+	return nil
+}
+
+// validateSymlink ensures a symlink target is relative and cannot refer to a
+// parent directory.
+func validateSymlink(target string) error {
+	if target == "" || filepath.IsAbs(target) || strings.HasPrefix(target, `\`) {
+		return fmt.Errorf("invalid symlink target: %q", target)
+	}
+	for _, seg := range strings.FieldsFunc(target, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return fmt.Errorf("invalid symlink target: %q", target)
+		}
+	}
+	return nil
+}
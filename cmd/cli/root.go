@@ -13,9 +13,16 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/alanshaw/buff/cmd/cli/delegation"
+	"github.com/alanshaw/buff/cmd/cli/key"
+	"github.com/alanshaw/buff/cmd/cli/locate"
+	"github.com/alanshaw/buff/cmd/cli/profile"
+	"github.com/alanshaw/buff/cmd/cli/repo"
+	"github.com/alanshaw/buff/cmd/cli/retrieve"
 	"github.com/alanshaw/buff/cmd/cli/space"
 	"github.com/alanshaw/buff/cmd/cli/upload"
 	"github.com/alanshaw/buff/pkg/build"
+	"github.com/alanshaw/buff/pkg/config"
 	"github.com/alanshaw/buff/pkg/presets"
 )
 
@@ -45,6 +52,9 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file path. Attempts to load from user config directory if not set e.g. ~/.config/"+configFilePath)
 
+	rootCmd.PersistentFlags().String("profile", "", "Config profile to use. Defaults to the profile set by \"buff profile use\"")
+	cobra.CheckErr(viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile")))
+
 	rootCmd.PersistentFlags().String("data-dir", filepath.Join(lo.Must(os.UserHomeDir()), ".buff"), "Upload service data directory")
 	cobra.CheckErr(viper.BindPFlag("repo.data_dir", rootCmd.PersistentFlags().Lookup("data-dir")))
 
@@ -93,6 +103,13 @@ func init() {
 	cobra.CheckErr(viper.BindPFlag("services.upload.url", rootCmd.Flags().Lookup("upload-service-url")))
 
 	// register all commands and their subcommands
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(delegation.Cmd)
+	rootCmd.AddCommand(key.Cmd)
+	rootCmd.AddCommand(locate.Cmd)
+	rootCmd.AddCommand(profile.Cmd)
+	rootCmd.AddCommand(repo.Cmd)
+	rootCmd.AddCommand(retrieve.Cmd)
 	rootCmd.AddCommand(space.Cmd)
 	rootCmd.AddCommand(upload.Cmd)
 }
@@ -123,6 +140,8 @@ func initConfig() {
 		// Don't error if config file is not found - it's optional
 		_ = viper.ReadInConfig()
 	}
+
+	cobra.CheckErr(config.ApplyProfile(viper.GetString("profile")))
 }
 
 func initLogging() {
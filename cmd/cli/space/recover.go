@@ -0,0 +1,64 @@
+package space
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/algorand/go-algorand-sdk/mnemonic"
+	"github.com/spf13/cobra"
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover <phrase>",
+	Short: "Recover a space from its recovery phrase",
+	Long: "Recover a space from the recovery phrase printed when it was created. " +
+		"The phrase may be passed as a single quoted argument or as separate words.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: cli.FXCommand(doRecover),
+}
+
+func init() {
+	recoverCmd.Flags().String("name", "", "Name of the space")
+}
+
+func doRecover(cmd *cobra.Command, args []string, id principal.Signer, delegationStore dlgstore.Store) error {
+	key, err := mnemonic.ToKey(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("invalid recovery phrase: %w", err)
+	}
+	signer, err := ed25519.FromRaw(key)
+	cobra.CheckErr(err)
+
+	for dlg, err := range delegationStore.List(cmd.Context(), id) {
+		cobra.CheckErr(err)
+		if dlg.Subject() != nil && dlg.Subject().DID() == signer.DID() && dlg.Command() == command.Top() {
+			cmd.Printf("✅ space already known: %s\n", signer.DID())
+			return nil
+		}
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	dlg, err := delegation.Delegate(
+		signer,
+		id.DID(),
+		signer,
+		command.Top(),
+		delegation.WithMetadata(ipld.Map{"name": name}),
+		delegation.WithNoExpiration(),
+	)
+	cobra.CheckErr(err)
+
+	err = delegationStore.Put(cmd.Context(), dlg)
+	cobra.CheckErr(err)
+
+	cmd.Println("Recovered space ID:")
+	cmd.Println(signer.DID())
+	return nil
+}
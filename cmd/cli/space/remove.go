@@ -23,9 +23,9 @@ func doRemove(cmd *cobra.Command, args []string, id principal.Signer, delegation
 	cobra.CheckErr(err)
 
 	n := 0
-	for dlg, err := range delegationStore.List(cmd.Context(), id) {
+	for dlg, err := range delegationStore.FindBySubject(cmd.Context(), space) {
 		cobra.CheckErr(err)
-		if dlg.Subject() != nil && dlg.Subject().DID() == space.DID() {
+		if dlg.Audience().DID() == id.DID() {
 			err := delegationStore.Del(cmd.Context(), dlg.Link())
 			cobra.CheckErr(err)
 			n++
@@ -12,5 +12,7 @@ var Cmd = &cobra.Command{
 func init() {
 	Cmd.AddCommand(createCmd)
 	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(recoverCmd)
 	Cmd.AddCommand(removeCmd)
+	Cmd.AddCommand(shareCmd)
 }
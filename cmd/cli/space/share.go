@@ -0,0 +1,121 @@
+package space
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	ucanlib "github.com/alanshaw/libracha/ucan"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/alanshaw/ucantone/ucan/container"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/spf13/cobra"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share <space-did> <audience-did>",
+	Short: "Share a space with another DID",
+	Long: "Delegate capabilities for a space to another DID. The delegations and " +
+		"their proofs are written as a UCAN container to the output file, or to " +
+		"stdout as base64 if no output file is given. The recipient can import " +
+		"the container to gain access to the space.",
+	Args: cobra.ExactArgs(2),
+	RunE: cli.FXCommand(doShare),
+}
+
+func init() {
+	shareCmd.Flags().StringSlice("can", nil, "Command to delegate e.g. /blob/add (may be repeated)")
+	shareCmd.Flags().String("expiration", "", "Duration the delegation is valid for e.g. 12h, 30d (default no expiration)")
+	shareCmd.Flags().StringP("output", "o", "", "Path to write the delegation container to")
+	shareCmd.MarkFlagRequired("can")
+}
+
+func doShare(cmd *cobra.Command, args []string, id principal.Signer, delegationStore dlgstore.Store) error {
+	space, err := did.Parse(args[0])
+	cobra.CheckErr(err)
+	audience, err := did.Parse(args[1])
+	cobra.CheckErr(err)
+
+	cans, _ := cmd.Flags().GetStringSlice("can")
+	expiration, _ := cmd.Flags().GetString("expiration")
+	output, _ := cmd.Flags().GetString("output")
+
+	options := []delegation.Option{delegation.WithNoExpiration()}
+	if expiration != "" {
+		ttl, err := parseDuration(expiration)
+		if err != nil {
+			return fmt.Errorf("invalid expiration: %w", err)
+		}
+		options = []delegation.Option{
+			delegation.WithExpiration(ucan.UTCUnixTimestamp(time.Now().Add(ttl).Unix())),
+		}
+	}
+
+	var dlgs, proofs []ucan.Delegation
+	seen := map[string]struct{}{}
+	for _, can := range cans {
+		c, err := command.Parse(can)
+		if err != nil {
+			return fmt.Errorf("invalid command %q: %w", can, err)
+		}
+
+		chain, _, err := ucanlib.ProofChain(cmd.Context(), delegationStore, id, c, space)
+		cobra.CheckErr(err)
+		if len(chain) == 0 {
+			return fmt.Errorf("no delegation found for %q on space: %s", c, space)
+		}
+		for _, p := range chain {
+			if _, ok := seen[p.Link().String()]; ok {
+				continue
+			}
+			seen[p.Link().String()] = struct{}{}
+			proofs = append(proofs, p)
+		}
+
+		dlg, err := delegation.Delegate(id, audience, space, c, options...)
+		cobra.CheckErr(err)
+		// keep a copy so that the delegation can be revoked later
+		err = delegationStore.PutIssued(cmd.Context(), dlg)
+		cobra.CheckErr(err)
+		dlgs = append(dlgs, dlg)
+	}
+
+	codec := container.Base64
+	if output != "" {
+		codec = container.Raw
+	}
+	b, err := container.Encode(codec, container.New(container.WithDelegations(append(dlgs, proofs...)...)))
+	cobra.CheckErr(err)
+
+	if output == "" {
+		cmd.PrintErrf("🤝 shared space %q with %q\n", space, audience)
+		fmt.Fprintln(cmd.OutOrStdout(), string(b))
+		return nil
+	}
+
+	err = os.WriteFile(output, b, 0644)
+	cobra.CheckErr(err)
+	cmd.Printf("🤝 shared space %q with %q\n", space, audience)
+	cmd.Printf("💾 wrote %d delegation(s) to %s\n", len(dlgs), output)
+	return nil
+}
+
+// parseDuration parses a duration string as [time.ParseDuration] does, with
+// additional support for a "d" (days) unit e.g. "30d".
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("parsing days: %w", err)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,86 @@
+package upload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alanshaw/buff/pkg/unixfs"
+	"github.com/ipfs/go-cid"
+)
+
+func isDir(path string) bool {
+	inf, err := os.Stat(path)
+	return err == nil && inf.IsDir()
+}
+
+// buildDAG builds a UnixFS DAG from the passed paths, emitting blocks as they
+// are created. A single directory becomes the root of the DAG, multiple paths
+// are wrapped in a directory, using their base names as entry names.
+func buildDAG(paths []string, emit unixfs.BlockEmitter) (cid.Cid, error) {
+	if len(paths) == 1 {
+		l, err := addPath(paths[0], emit)
+		if err != nil {
+			return cid.Undef, err
+		}
+		return l.Cid, nil
+	}
+	var entries []unixfs.Link
+	for _, p := range paths {
+		l, err := addPath(p, emit)
+		if err != nil {
+			return cid.Undef, err
+		}
+		l.Name = filepath.Base(p)
+		entries = append(entries, l)
+	}
+	l, err := unixfs.BuildDirectory(entries, emit)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("building wrapping directory: %w", err)
+	}
+	return l.Cid, nil
+}
+
+func addPath(path string, emit unixfs.BlockEmitter) (unixfs.Link, error) {
+	inf, err := os.Stat(path)
+	if err != nil {
+		return unixfs.Link{}, err
+	}
+	if !inf.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return unixfs.Link{}, err
+		}
+		defer f.Close()
+		log.Debugf("adding file: %s", path)
+		l, err := unixfs.BuildFile(f, emit)
+		if err != nil {
+			return unixfs.Link{}, fmt.Errorf("building file %s: %w", path, err)
+		}
+		return l, nil
+	}
+
+	dirents, err := os.ReadDir(path)
+	if err != nil {
+		return unixfs.Link{}, err
+	}
+	var entries []unixfs.Link
+	for _, d := range dirents {
+		p := filepath.Join(path, d.Name())
+		if !d.IsDir() && !d.Type().IsRegular() {
+			log.Warnf("skipping %s: not a regular file or directory", p)
+			continue
+		}
+		l, err := addPath(p, emit)
+		if err != nil {
+			return unixfs.Link{}, err
+		}
+		l.Name = d.Name()
+		entries = append(entries, l)
+	}
+	l, err := unixfs.BuildDirectory(entries, emit)
+	if err != nil {
+		return unixfs.Link{}, fmt.Errorf("building directory %s: %w", path, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,236 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+type eventType string
+
+const (
+	// eventResuming is emitted when an upload found in the journal is resumed.
+	eventResuming eventType = "resuming"
+	// eventBlob is emitted when work on a blob begins.
+	eventBlob eventType = "blob"
+	// eventAdded is emitted when the /blob/add invocation has succeeded.
+	eventAdded eventType = "added"
+	// eventAllocated is emitted when space has been allocated for a blob. The
+	// URL is empty if the provider already has the blob.
+	eventAllocated eventType = "allocated"
+	// eventUploading is emitted periodically while blob data is being PUT.
+	eventUploading eventType = "uploading"
+	// eventConcluded is emitted when the /http/put receipt has been sent to the
+	// service.
+	eventConcluded eventType = "concluded"
+	// eventAccepted is emitted when the service has accepted the blob and issued
+	// a location commitment.
+	eventAccepted eventType = "accepted"
+	// eventLocation is emitted for each URL the blob can be retrieved from.
+	eventLocation eventType = "location"
+	// eventIndexed is emitted when the DAG index has been published.
+	eventIndexed eventType = "indexed"
+	// eventRegistered is emitted when the upload has been registered.
+	eventRegistered eventType = "registered"
+	// eventComplete is emitted when all steps of the upload have completed.
+	eventComplete eventType = "complete"
+)
+
+// event describes progress made by an upload.
+type event struct {
+	Type  eventType `json:"type"`
+	Root  string    `json:"root,omitempty"`
+	Space string    `json:"space,omitempty"`
+	// Name describes the blob, e.g. "shard 1/3" or "index".
+	Name string `json:"name,omitempty"`
+	// Blob is the multibase encoded multihash of the blob.
+	Blob string `json:"blob,omitempty"`
+	Size uint64 `json:"size,omitempty"`
+	// Bytes is the number of bytes of the blob uploaded so far. It is always
+	// present, so that 0 bytes uploaded can be distinguished from no progress.
+	Bytes    uint64 `json:"bytes"`
+	Provider string `json:"provider,omitempty"`
+	URL      string `json:"url,omitempty"`
+	// Task is the link to the task relevant to the event, e.g. the /blob/accept
+	// task for "added" and the /http/put task for "concluded".
+	Task       string `json:"task,omitempty"`
+	Commitment string `json:"commitment,omitempty"`
+	Index      string `json:"index,omitempty"`
+	Shards     int    `json:"shards,omitempty"`
+}
+
+// reporter presents upload events to the user.
+type reporter interface {
+	report(e event)
+	// close finishes any output in progress, such as a progress bar.
+	close()
+}
+
+// newReporter creates a reporter that writes newline delimited JSON events to
+// stdout if the --json flag is set, and human readable output otherwise.
+func newReporter(cmd *cobra.Command) reporter {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	if asJSON {
+		return &jsonReporter{enc: json.NewEncoder(cmd.OutOrStdout())}
+	}
+	return newTextReporter(cmd.OutOrStderr())
+}
+
+type jsonReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (r *jsonReporter) report(e event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		log.Warnw("writing event", "error", err)
+	}
+}
+
+func (r *jsonReporter) close() {}
+
+// textReporter prints a line for each event, and a progress bar for blobs
+// that are being uploaded when writing to a terminal.
+type textReporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	tty bool
+	// uploads are the latest uploading events for blobs being uploaded.
+	uploads map[string]event
+	drawn   bool
+}
+
+func newTextReporter(w io.Writer) *textReporter {
+	tty := false
+	if f, ok := w.(*os.File); ok {
+		tty = isatty.IsTerminal(f.Fd())
+	}
+	return &textReporter{w: w, tty: tty, uploads: map[string]event{}}
+}
+
+func (r *textReporter) report(e event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clear()
+
+	switch e.Type {
+	case eventResuming:
+		r.printf("⏯️ resuming upload of %s to space %q\n", e.Root, e.Space)
+	case eventBlob:
+		r.printf("📦 %s: %q (%d bytes)\n", e.Name, e.Blob, e.Size)
+	case eventAdded:
+		r.printf("➕ added %q, site will be committed by task: %s\n", e.Blob, e.Task)
+	case eventAllocated:
+		if e.URL == "" {
+			r.printf("✅ skipping upload, %q already has %q.\n", e.Provider, e.Blob)
+		} else {
+			r.printf("⬆️ uploading %q to %q (%s)\n", e.Blob, e.Provider, e.URL)
+		}
+	case eventUploading:
+		if e.Bytes >= e.Size {
+			delete(r.uploads, e.Blob)
+		} else {
+			r.uploads[e.Blob] = e
+		}
+	case eventConcluded:
+		r.printf("🧾 issued receipt for completed %q task: %s\n", "/http/put", e.Task)
+	case eventAccepted:
+		r.printf("✍️ location commitment: %s\n", e.Commitment)
+	case eventLocation:
+		r.printf("📍 blob location: %s\n", e.URL)
+	case eventIndexed:
+		r.printf("🔎 published index %s\n", e.Index)
+	case eventRegistered:
+		r.printf("📝 registered upload %s with %d shard(s)\n", e.Root, e.Shards)
+	case eventComplete:
+		if e.Shards == 0 {
+			r.printf("✅ upload complete! Blob %q accepted in space %q\n", e.Blob, e.Space)
+		} else {
+			r.printf("✅ upload complete! %d shard(s) accepted in space %q\n", e.Shards, e.Space)
+		}
+		r.printf("🌱 %s\n", e.Root)
+	}
+
+	r.draw()
+}
+
+func (r *textReporter) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clear()
+}
+
+func (r *textReporter) printf(format string, a ...any) {
+	fmt.Fprintf(r.w, format, a...)
+}
+
+// clear removes the progress bar from the terminal.
+func (r *textReporter) clear() {
+	if r.drawn {
+		r.printf("\r\033[K")
+		r.drawn = false
+	}
+}
+
+// draw renders a progress bar for all blobs currently being uploaded.
+func (r *textReporter) draw() {
+	if !r.tty || len(r.uploads) == 0 {
+		return
+	}
+	var bytes, size uint64
+	for _, e := range r.uploads {
+		bytes += e.Bytes
+		size += e.Size
+	}
+	const width = 30
+	filled := int(float64(width) * float64(bytes) / float64(size))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	r.printf("⬆️ [%s] %3d%% %s / %s (%d blob(s))", bar, bytes*100/size, formatBytes(bytes), formatBytes(size), len(r.uploads))
+	r.drawn = true
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressReader reports the number of bytes read from the underlying reader
+// at most once per interval, and when the reader is exhausted.
+type progressReader struct {
+	r        io.Reader
+	n        uint64
+	interval time.Duration
+	last     time.Time
+	report   func(n uint64)
+}
+
+func newProgressReader(r io.Reader, report func(n uint64)) *progressReader {
+	return &progressReader{r: r, interval: 250 * time.Millisecond, report: report}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += uint64(n)
+	if err == io.EOF || time.Since(p.last) >= p.interval {
+		p.last = time.Now()
+		p.report(p.n)
+	}
+	return n, err
+}
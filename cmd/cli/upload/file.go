@@ -0,0 +1,78 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/multiformats/go-multihash"
+)
+
+// spool copies the data from the passed reader to a new file in the passed
+// directory. The returned file is positioned at the start and should be removed
+// by the caller when no longer required.
+func spool(dir string, r io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("spooling data to staging file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("seeking staging file: %w", err)
+	}
+	return f, nil
+}
+
+// hashFile calculates the sha2-256 multihash of the file contents in a single
+// streaming pass, returning the digest and the number of bytes read.
+func hashFile(f *os.File) (multihash.Multihash, uint64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("seeking file: %w", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("hashing file: %w", err)
+	}
+	digest, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return nil, 0, fmt.Errorf("encoding multihash: %w", err)
+	}
+	return digest, uint64(n), nil
+}
+
+// openBlob opens the file containing the data for the passed blob. If verify is
+// true and the file was not staged by the upload, the file is re-hashed to
+// ensure it has not changed since the upload began. Staged files are written
+// by buff and are not re-hashed.
+func openBlob(b *upstore.Blob, verify bool) (*os.File, error) {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening blob data: %w", err)
+	}
+	if verify && !b.Staged {
+		digest, size, err := hashFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !bytes.Equal(digest, b.Digest) || size != b.Size {
+			f.Close()
+			return nil, fmt.Errorf("file %q has changed since the upload began", b.Path)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("seeking file: %w", err)
+		}
+	}
+	return f, nil
+}
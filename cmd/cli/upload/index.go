@@ -0,0 +1,46 @@
+package upload
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alanshaw/buff/pkg/blobindex"
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// buildIndex creates a sharded DAG index for the DAG with the passed root from
+// the block positions recorded when the shards were written.
+func buildIndex(root ucan.Link, shards []*shard) *blobindex.ShardedDAGIndex {
+	index := blobindex.New(root)
+	for _, s := range shards {
+		// the shard itself is a slice of the shard
+		index.SetSlice(s.digest, s.digest, blobindex.Position{Offset: 0, Length: s.size})
+		for _, slc := range s.slices {
+			index.SetSlice(s.digest, slc.Digest, slc.Position)
+		}
+	}
+	return index
+}
+
+// stageIndex archives the index to a file in the passed directory so that it
+// can be uploaded as a blob.
+func stageIndex(dir string, index *blobindex.ShardedDAGIndex) (upstore.Blob, error) {
+	f, err := os.CreateTemp(dir, "index-*.car")
+	if err != nil {
+		return upstore.Blob{}, fmt.Errorf("creating index file: %w", err)
+	}
+	defer f.Close()
+
+	if err := index.Archive(f); err != nil {
+		os.Remove(f.Name())
+		return upstore.Blob{}, fmt.Errorf("archiving index: %w", err)
+	}
+
+	digest, size, err := hashFile(f)
+	if err != nil {
+		os.Remove(f.Name())
+		return upstore.Blob{}, fmt.Errorf("hashing index: %w", err)
+	}
+	return upstore.Blob{Digest: digest, Size: size, Path: f.Name(), Staged: true}, nil
+}
@@ -0,0 +1,227 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/alanshaw/buff/pkg/client"
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/ipfs/go-cid"
+)
+
+// uploader performs the steps of an upload, recording progress in the upload
+// journal so that an interrupted upload can be resumed.
+type uploader struct {
+	client      *client.Client
+	uploadStore upstore.Store
+	reporter    reporter
+	// concurrency is the maximum number of blobs uploaded in parallel.
+	concurrency int
+	// verify causes unstaged files to be re-hashed before they are uploaded, to
+	// detect changes made since the upload began.
+	verify bool
+}
+
+// run completes any outstanding steps of the upload. The journal entry and any
+// staged files are removed once the upload is complete.
+func (u *uploader) run(ctx context.Context, upload *upstore.Upload) error {
+	if err := u.persist(upload); err != nil {
+		return err
+	}
+
+	if err := u.uploadBlobs(ctx, upload); err != nil {
+		return err
+	}
+
+	if upload.Index != nil && !upload.IndexAdded {
+		index := cid.NewCidV1(car.Codec, upload.Index.Digest)
+		if err := u.client.AddIndex(ctx, upload.Space, index); err != nil {
+			return fmt.Errorf("publishing index: %w", err)
+		}
+		u.reporter.report(event{Type: eventIndexed, Root: upload.Root.String(), Index: index.String()})
+		upload.IndexAdded = true
+		if err := u.persist(upload); err != nil {
+			return err
+		}
+	}
+
+	if !upload.Registered {
+		if err := u.client.AddUpload(ctx, upload.Space, upload.Root, shardLinks(upload)); err != nil {
+			return fmt.Errorf("registering upload: %w", err)
+		}
+		u.reporter.report(event{Type: eventRegistered, Root: upload.Root.String(), Space: upload.Space.String(), Shards: len(upload.Blobs)})
+		upload.Registered = true
+		if err := u.persist(upload); err != nil {
+			return err
+		}
+	}
+
+	if err := u.uploadStore.Del(ctx, upload.Space, upload.Root); err != nil {
+		return fmt.Errorf("removing upload journal: %w", err)
+	}
+	removeStaged(*upload)
+
+	complete := event{Type: eventComplete, Root: upload.Root.String(), Space: upload.Space.String()}
+	if upload.Index == nil {
+		complete.Blob = digestutil.Format(upload.Blobs[0].Digest)
+	} else {
+		complete.Shards = len(upload.Blobs)
+	}
+	u.reporter.report(complete)
+
+	return nil
+}
+
+// uploadBlobs uploads the blobs of the upload, including the index, using a
+// pool of workers so that the steps for different blobs happen in parallel.
+// Errors for all blobs that failed are returned together.
+func (u *uploader) uploadBlobs(ctx context.Context, upload *upstore.Upload) error {
+	type job struct {
+		name   string
+		blob   upstore.Blob
+		commit func(upstore.Blob)
+	}
+	var jobs []job
+	for i, b := range upload.Blobs {
+		name := "blob"
+		if upload.Index != nil {
+			name = fmt.Sprintf("shard %d/%d", i+1, len(upload.Blobs))
+		}
+		jobs = append(jobs, job{name, b, func(b upstore.Blob) { upload.Blobs[i] = b }})
+	}
+	if upload.Index != nil {
+		jobs = append(jobs, job{"index", *upload.Index, func(b upstore.Blob) { *upload.Index = b }})
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	sem := make(chan struct{}, max(u.concurrency, 1))
+	for _, j := range jobs {
+		if j.blob.Accepted {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			mu.Lock()
+			errs = append(errs, ctx.Err())
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			b := j.blob
+			u.reporter.report(event{Type: eventBlob, Name: j.name, Blob: digestutil.Format(b.Digest), Size: b.Size})
+			save := func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				j.commit(b)
+				return u.persist(upload)
+			}
+			if err := u.uploadBlob(ctx, upload.Space, &b, save); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("uploading %s: %w", j.name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// persist writes the current state of the upload to the journal.
+func (u *uploader) persist(upload *upstore.Upload) error {
+	upload.Updated = time.Now().Unix()
+	// not bound to the command context so that progress made before the upload
+	// was cancelled is still recorded.
+	if err := u.uploadStore.Put(context.Background(), *upload); err != nil {
+		return fmt.Errorf("writing upload journal: %w", err)
+	}
+	return nil
+}
+
+// shardLinks returns the links to the shards of the upload. A raw upload is
+// its own shard.
+func shardLinks(upload *upstore.Upload) []ucan.Link {
+	if upload.Index == nil {
+		return []ucan.Link{upload.Root}
+	}
+	var links []ucan.Link
+	for _, b := range upload.Blobs {
+		links = append(links, cid.NewCidV1(car.Codec, b.Digest))
+	}
+	return links
+}
+
+// restage carries the progress recorded for an earlier attempt at an upload
+// over to a freshly staged copy of the same upload. Blobs are matched by
+// digest, and files staged by the earlier attempt are replaced by the fresh
+// ones.
+func restage(prev, fresh upstore.Upload) upstore.Upload {
+	staged := map[string]upstore.Blob{}
+	for _, b := range fresh.Blobs {
+		staged[string(b.Digest)] = b
+	}
+	if fresh.Index != nil {
+		staged[string(fresh.Index.Digest)] = *fresh.Index
+	}
+
+	update := func(b *upstore.Blob) {
+		f, ok := staged[string(b.Digest)]
+		if !ok {
+			return
+		}
+		if b.Staged && b.Path != f.Path {
+			os.Remove(b.Path)
+		}
+		b.Path, b.Staged = f.Path, f.Staged
+		delete(staged, string(b.Digest))
+	}
+	for i := range prev.Blobs {
+		update(&prev.Blobs[i])
+	}
+	if prev.Index != nil {
+		update(prev.Index)
+	}
+
+	// anything left over was not part of the earlier attempt
+	for _, b := range staged {
+		if b.Staged {
+			os.Remove(b.Path)
+		}
+	}
+	return prev
+}
+
+// removeStaged removes the files staged for the upload.
+func removeStaged(upload upstore.Upload) {
+	blobs := append([]upstore.Blob{}, upload.Blobs...)
+	if upload.Index != nil {
+		blobs = append(blobs, *upload.Index)
+	}
+	for _, b := range blobs {
+		if !b.Staged {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+			log.Warnw("removing staged file", "path", b.Path, "error", err)
+		}
+	}
+}
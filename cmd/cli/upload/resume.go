@@ -0,0 +1,120 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alanshaw/buff/pkg/client"
+	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/fx/cli"
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [<root>]",
+	Short: "Resume interrupted uploads",
+	Long: "Resume uploads that were interrupted before they completed. If a root " +
+		"CID is given only uploads of that root are resumed, otherwise all " +
+		"incomplete uploads are resumed.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: cli.FXCommand(doResume),
+}
+
+func init() {
+	resumeCmd.Flags().Bool("json", false, "Output newline delimited JSON events to stdout")
+}
+
+func doResume(cmd *cobra.Command, args []string, c *client.Client, uploadStore upstore.Store, uploadConfig app.UploadConfig) error {
+	var root cid.Cid
+	if len(args) > 0 {
+		var err error
+		root, err = cid.Parse(args[0])
+		cobra.CheckErr(err)
+	}
+
+	// collect the uploads first, they are modified as they are resumed
+	var uploads []upstore.Upload
+	for upload, err := range uploadStore.List(cmd.Context()) {
+		cobra.CheckErr(err)
+		if root.Defined() && upload.Root != root {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	if len(uploads) == 0 {
+		if root.Defined() {
+			return fmt.Errorf("no incomplete upload found for root: %s", root)
+		}
+		cmd.Println("✅ no incomplete uploads")
+		return nil
+	}
+
+	u := &uploader{
+		client:      c,
+		uploadStore: uploadStore,
+		reporter:    newReporter(cmd),
+		concurrency: uploadConfig.Concurrency,
+		verify:      true,
+	}
+	defer u.reporter.close()
+	// a failed upload does not stop the others from being resumed
+	var errs []error
+	for _, upload := range uploads {
+		u.reporter.report(event{Type: eventResuming, Root: upload.Root.String(), Space: upload.Space.String()})
+		if err := u.run(cmd.Context(), &upload); err != nil {
+			errs = append(errs, fmt.Errorf("resuming upload %s: %w", upload.Root, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List incomplete uploads and their progress",
+	Args:  cobra.NoArgs,
+	RunE:  cli.FXCommand(doStatus),
+}
+
+func doStatus(cmd *cobra.Command, uploadStore upstore.Store) error {
+	n := 0
+	for upload, err := range uploadStore.List(cmd.Context()) {
+		cobra.CheckErr(err)
+		n++
+
+		cmd.Printf("🌱 %s\n", upload.Root)
+		cmd.Printf("   space:   %s\n", upload.Space)
+		cmd.Printf("   started: %s\n", time.Unix(upload.Created, 0).Format(time.RFC3339))
+		cmd.Printf("   updated: %s\n", time.Unix(upload.Updated, 0).Format(time.RFC3339))
+		for i, b := range upload.Blobs {
+			cmd.Printf("   📦 blob %d/%d %q: %s\n", i+1, len(upload.Blobs), digestutil.Format(b.Digest), blobStatus(b))
+		}
+		if upload.Index != nil {
+			cmd.Printf("   🗂️ index %q: %s\n", digestutil.Format(upload.Index.Digest), blobStatus(*upload.Index))
+			cmd.Printf("   🔎 index published: %s\n", check(upload.IndexAdded))
+		}
+		cmd.Printf("   📝 registered: %s\n", check(upload.Registered))
+	}
+	if n == 0 {
+		cmd.Println("✅ no incomplete uploads")
+	}
+	return nil
+}
+
+// blobStatus describes the steps of the blob upload that have been completed.
+func blobStatus(b upstore.Blob) string {
+	if b.Address == nil && b.SiteTask != nil {
+		return fmt.Sprintf("added %s, accepted %s", check(true), check(b.Accepted))
+	}
+	return fmt.Sprintf("added %s, put %s, concluded %s, accepted %s", check(b.SiteTask != nil), check(b.Put), check(b.Concluded), check(b.Accepted))
+}
+
+func check(done bool) string {
+	if done {
+		return "✔"
+	}
+	return "✘"
+}
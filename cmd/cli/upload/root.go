@@ -1,306 +1,272 @@
 package upload
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/alanshaw/buff/pkg/client"
+	"github.com/alanshaw/buff/pkg/config"
 	"github.com/alanshaw/buff/pkg/config/app"
 	"github.com/alanshaw/buff/pkg/fx/cli"
-	rcpt_client "github.com/alanshaw/buff/pkg/receipt"
-	dstore "github.com/alanshaw/buff/pkg/store/delegation"
-	assert_caps "github.com/alanshaw/libracha/capabilities/assert"
-	"github.com/alanshaw/libracha/capabilities/blob"
-	http_caps "github.com/alanshaw/libracha/capabilities/http"
-	ucan_caps "github.com/alanshaw/libracha/capabilities/ucan"
+	"github.com/alanshaw/buff/pkg/store"
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
 	"github.com/alanshaw/libracha/digestutil"
-	ucanlib "github.com/alanshaw/libracha/ucan"
-	"github.com/alanshaw/ucantone/client"
 	"github.com/alanshaw/ucantone/did"
-	"github.com/alanshaw/ucantone/execution"
-	"github.com/alanshaw/ucantone/ipld"
-	"github.com/alanshaw/ucantone/ipld/datamodel"
-	"github.com/alanshaw/ucantone/principal"
-	"github.com/alanshaw/ucantone/principal/ed25519"
-	"github.com/alanshaw/ucantone/result"
-	"github.com/alanshaw/ucantone/ucan"
-	"github.com/alanshaw/ucantone/ucan/delegation"
-	"github.com/alanshaw/ucantone/ucan/delegation/policy"
 	"github.com/alanshaw/ucantone/ucan/invocation"
-	"github.com/alanshaw/ucantone/ucan/receipt"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/multiformats/go-multihash"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var log = logging.Logger("cmd/upload")
 
 var Cmd = &cobra.Command{
-	Use:     "upload <space-did> [<file-path>]",
+	Use:     "upload <space-did> [<path>...]",
 	Aliases: []string{"up"},
 	Short:   "Upload files to the Storacha Network",
-	Args:    cobra.MinimumNArgs(1),
-	RunE:    cli.FXCommand(doUpload),
+	Long: "Upload files to the Storacha Network. A single file (or data piped to " +
+		"stdin) is uploaded as a raw blob. Directories and multiple files are " +
+		"packed into a UnixFS DAG, serialized as CAR shards and each shard is " +
+		"uploaded as a blob.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: cli.FXCommand(doUpload),
 }
 
-func doUpload(cmd *cobra.Command, args []string, id principal.Signer, serviceConfig app.ExternalServicesConfig, delegationStore dstore.Store) error {
+func init() {
+	Cmd.Flags().Uint64("shard-size", defaultShardSize, "Maximum size in bytes of each CAR shard when uploading a DAG")
+	Cmd.Flags().Bool("json", false, "Output newline delimited JSON events to stdout")
+	Cmd.PersistentFlags().Int("concurrency", config.DefaultUploadConcurrency, "Maximum number of blobs to upload in parallel")
+	cobra.CheckErr(viper.BindPFlag("upload.concurrency", Cmd.PersistentFlags().Lookup("concurrency")))
+	Cmd.AddCommand(resumeCmd)
+	Cmd.AddCommand(statusCmd)
+}
+
+func doUpload(cmd *cobra.Command, args []string, c *client.Client, uploadStore upstore.Store, storageConfig app.UploadStorageConfig, uploadConfig app.UploadConfig) error {
 	space, err := did.Parse(args[0])
 	cobra.CheckErr(err)
 
-	var data []byte
-	if len(args) == 1 {
-		b, err := io.ReadAll(cmd.InOrStdin())
+	err = os.MkdirAll(storageConfig.StagingDir, 0755)
+	cobra.CheckErr(err)
+
+	var upload upstore.Upload
+	paths := args[1:]
+	if len(paths) > 1 || (len(paths) == 1 && isDir(paths[0])) {
+		shardSize, err := cmd.Flags().GetUint64("shard-size")
+		cobra.CheckErr(err)
+		upload, err = stageDAG(storageConfig.StagingDir, space, paths, shardSize, uploadConfig.Concurrency)
 		cobra.CheckErr(err)
-		data = b
 	} else {
-		b, err := os.ReadFile(args[1])
+		upload, err = stageBlob(cmd, storageConfig.StagingDir, space, paths)
 		cobra.CheckErr(err)
-		data = b
 	}
 
-	digest, err := multihash.Sum(data, multihash.SHA2_256, -1)
-	cobra.CheckErr(err)
-
-	matcher := ucanlib.NewDelegationMatcher(delegationStore)
-	proofs, proofLinks, err := ucanlib.ProofChain(cmd.Context(), matcher, id, blob.AddCommand, space)
-	cobra.CheckErr(err)
+	u := &uploader{
+		client:      c,
+		uploadStore: uploadStore,
+		reporter:    newReporter(cmd),
+		concurrency: uploadConfig.Concurrency,
+	}
 
-	if len(proofs) == 0 {
-		return fmt.Errorf("missing %q delegations for space: %s", blob.AddCommand, space)
+	// an earlier attempt to upload the same content may have been interrupted,
+	// in which case carry on from where it left off.
+	prev, err := uploadStore.Get(cmd.Context(), space, upload.Root)
+	if err == nil {
+		u.reporter.report(event{Type: eventResuming, Root: upload.Root.String(), Space: space.String()})
+		upload = restage(prev, upload)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("getting upload journal: %w", err)
 	}
 
-	inv, err := blob.Add.Invoke(
-		id,
-		space,
-		&blob.AddArguments{
-			Blob: blob.Blob{
-				Digest: digest,
-				Size:   uint64(len(data)),
-			},
-		},
-		invocation.WithAudience(serviceConfig.Upload.ID),
-		invocation.WithProofs(proofLinks...),
-	)
-	cobra.CheckErr(err)
+	defer u.reporter.close()
+	return u.run(cmd.Context(), &upload)
+}
 
-	// create required invocation delegations
-	// TODO: get proof chain for these as well and add to invocation - for now it
-	// is fine as we know we have top authority over the space so this delegation
-	// will be included already.
-	allocDlg, err := delegation.Delegate(
-		id,
-		serviceConfig.Upload.ID,
-		space,
-		blob.AllocateCommand,
-		delegation.WithPolicyBuilder(
-			policy.And(
-				policy.Equal(".blob.digest", []byte(digest)),
-				policy.Equal(".blob.size", len(data)),
-			),
-		),
+// stageBlob prepares the upload of a single file, or data piped to stdin, as a
+// raw blob. Data from stdin is spooled to the staging directory so that it is
+// still available if the upload needs to be resumed.
+func stageBlob(cmd *cobra.Command, stagingDir string, space did.DID, paths []string) (upstore.Upload, error) {
+	var (
+		f      *os.File
+		err    error
+		staged bool
 	)
-	cobra.CheckErr(err)
-
-	client, err := client.NewHTTP(serviceConfig.Upload.URL)
-	cobra.CheckErr(err)
+	if len(paths) == 0 {
+		f, err = spool(stagingDir, cmd.InOrStdin())
+		if err != nil {
+			return upstore.Upload{}, err
+		}
+		staged = true
+	} else {
+		path, err := filepath.Abs(paths[0])
+		if err != nil {
+			return upstore.Upload{}, err
+		}
+		f, err = os.Open(path)
+		if err != nil {
+			return upstore.Upload{}, err
+		}
+	}
+	defer f.Close()
 
-	request := execution.NewRequest(
-		cmd.Context(),
-		inv,
-		execution.WithProofs(proofs...),
-		execution.WithDelegations(allocDlg),
-	)
+	digest, size, err := hashFile(f)
+	if err != nil {
+		if staged {
+			os.Remove(f.Name())
+		}
+		return upstore.Upload{}, err
+	}
 
-	response, err := client.Execute(request)
-	cobra.CheckErr(err)
+	return upstore.Upload{
+		Root:    cid.NewCidV1(cid.Raw, digest),
+		Space:   space,
+		Blobs:   []upstore.Blob{{Digest: digest, Size: size, Path: f.Name(), Staged: staged}},
+		Created: time.Now().Unix(),
+	}, nil
+}
 
-	addOut, err := result.MapResultR1(
-		response.Out(),
-		func(o ipld.Any) (blob.AddOK, error) {
-			model := blob.AddOK{}
-			err = datamodel.Rebind(datamodel.NewAny(o), &model)
-			return model, err
-		},
-		func(x ipld.Any) (error, error) {
-			return nil, fmt.Errorf("failed %q task: %+v", blob.AddCommand, x)
-		},
-	)
-	cobra.CheckErr(err)
-	addOK, _ := result.Unwrap(addOut)
+// stageDAG packs the passed paths into a UnixFS DAG, splits it into CAR shards
+// and builds an index for it. The shards and the index are written to the
+// staging directory.
+func stageDAG(stagingDir string, space did.DID, paths []string, shardSize uint64, concurrency int) (upstore.Upload, error) {
+	sharder, err := newSharder(stagingDir, shardSize, concurrency)
+	if err != nil {
+		return upstore.Upload{}, err
+	}
 
-	// Find /http/put invocation
-	var httpPutInv ucan.Invocation
-	for _, inv := range response.Metadata().Invocations() {
-		if inv.Command() == http_caps.PutCommand {
-			httpPutInv = inv
-			break
-		}
+	root, err := buildDAG(paths, sharder.put)
+	if err != nil {
+		sharder.cleanup()
+		return upstore.Upload{}, err
 	}
-	if httpPutInv == nil {
-		return fmt.Errorf("missing %q invocation in response", http_caps.PutCommand)
+
+	shards, err := sharder.close()
+	if err != nil {
+		sharder.cleanup()
+		return upstore.Upload{}, err
 	}
-	blobProvider, err := extractBlobProviderKey(httpPutInv)
+
+	index, err := stageIndex(stagingDir, buildIndex(root, shards))
 	if err != nil {
-		return fmt.Errorf("extracting blob provider key: %w", err)
+		sharder.cleanup()
+		return upstore.Upload{}, err
 	}
 
-	// Find allocation receipt in the response metadata
-	var allocRcpt ucan.Receipt
-	for _, inv := range response.Metadata().Invocations() {
-		if inv.Command() != blob.AllocateCommand {
-			continue
-		}
-		rcpt, ok := response.Metadata().Receipt(inv.Task().Link())
-		if ok {
-			allocRcpt = rcpt
-			break
-		}
+	upload := upstore.Upload{
+		Root:    root,
+		Space:   space,
+		Index:   &index,
+		Created: time.Now().Unix(),
 	}
-	if allocRcpt == nil {
-		return fmt.Errorf("missing %q receipt in response", blob.AllocateCommand)
+	for _, s := range shards {
+		upload.Blobs = append(upload.Blobs, upstore.Blob{Digest: s.digest, Size: s.size, Path: s.path, Staged: true})
 	}
+	return upload, nil
+}
 
-	allocOut, err := result.MapResultR1(
-		allocRcpt.Out(),
-		func(o ipld.Any) (blob.AllocateOK, error) {
-			model := blob.AllocateOK{}
-			err = datamodel.Rebind(datamodel.NewAny(o), &model)
-			return model, err
-		},
-		func(x ipld.Any) (error, error) {
-			return nil, fmt.Errorf("failed %q task: %+v", blob.AllocateCommand, x)
-		},
-	)
-	cobra.CheckErr(err)
-	allocOK, _ := result.Unwrap(allocOut)
-
-	var httpPutRcpt ucan.Receipt
-	if allocOK.Address == nil {
-		cmd.Printf("✅ skipping upload, %q already has %q.\n", allocRcpt.Issuer().DID(), digestutil.Format(digest))
-	} else {
-		cmd.Printf("⬆️ uploading %q to %q (%s)\n", digestutil.Format(digest), allocRcpt.Issuer().DID(), allocOK.Address.URL.URL().String())
-		putReq, err := http.NewRequestWithContext(cmd.Context(), http.MethodPut, allocOK.Address.URL.URL().String(), bytes.NewReader(data))
-		cobra.CheckErr(err)
-		for k, v := range allocOK.Address.Headers {
-			putReq.Header.Set(k, v)
+// uploadBlob adds the blob to the space, uploading the data if the service does
+// not already have it, and waits for it to be accepted. The passed save
+// function is called to record each completed step in the upload journal, and
+// steps that were completed previously are skipped.
+func (u *uploader) uploadBlob(ctx context.Context, space did.DID, b *upstore.Blob, save func() error) error {
+	digest := multihash.Multihash(b.Digest)
+
+	if b.SiteTask == nil {
+		alloc, err := u.client.AddBlob(ctx, space, digest, b.Size)
+		if err != nil {
+			return err
 		}
-		putRes, err := http.DefaultClient.Do(putReq)
-		cobra.CheckErr(err)
-		defer putRes.Body.Close()
 
-		if putRes.StatusCode < 200 || putRes.StatusCode >= 300 {
-			body, _ := io.ReadAll(putRes.Body)
-			return fmt.Errorf("upload failed with status %d: %s", putRes.StatusCode, string(body))
+		b.PutInvocation, err = invocation.Encode(alloc.Put)
+		if err != nil {
+			return fmt.Errorf("encoding %q invocation: %w", alloc.Put.Command(), err)
+		}
+		site := alloc.Site
+		b.SiteTask = &site
+		u.reporter.report(event{Type: eventAdded, Blob: digestutil.Format(digest), Size: b.Size, Task: site.String()})
+
+		allocated := event{Type: eventAllocated, Blob: digestutil.Format(digest), Size: b.Size, Provider: alloc.Provider.String()}
+		if alloc.Address != nil {
+			b.Address = &upstore.Address{
+				URL:     alloc.Address.URL.String(),
+				Headers: alloc.Address.Headers,
+			}
+			allocated.URL = b.Address.URL
+		}
+		u.reporter.report(allocated)
+		if err := save(); err != nil {
+			return err
 		}
-
-		cmd.Printf("🧾 issuing receipt for completed %q task\n", http_caps.PutCommand)
-		httpPutRcpt, err = receipt.Issue(
-			blobProvider,
-			httpPutInv.Task().Link(),
-			result.OK[ipld.Map, ipld.Any](ipld.Map{}),
-		)
-		cobra.CheckErr(err)
-
-		concludeInv, err := ucan_caps.Conclude.Invoke(
-			id,
-			id,
-			&ucan_caps.ConcludeArguments{
-				Receipt: httpPutRcpt.Link(),
-			},
-			invocation.WithAudience(serviceConfig.Upload.ID),
-		)
-		cobra.CheckErr(err)
-
-		accDlg, err := delegation.Delegate(
-			id,
-			serviceConfig.Upload.ID,
-			space,
-			blob.AcceptCommand,
-			delegation.WithPolicyBuilder(
-				policy.And(
-					policy.Equal(".blob.digest", []byte(digest)),
-					policy.Equal(".blob.size", len(data)),
-				),
-			),
-		)
-		cobra.CheckErr(err)
-
-		request := execution.NewRequest(
-			cmd.Context(),
-			concludeInv,
-			execution.WithDelegations(accDlg),
-			execution.WithReceipts(httpPutRcpt),
-		)
-		_, err = client.Execute(request)
-		cobra.CheckErr(err)
 	}
 
-	cmd.Printf("⏳ awaiting site from %q task: %s\n", blob.AcceptCommand, addOK.Site.Task)
-
-	rcptClient := rcpt_client.New(serviceConfig.Upload.URL.JoinPath("receipt"))
-	accRcpt, accRcptCt, err := rcptClient.Poll(cmd.Context(), addOK.Site.Task)
-	cobra.CheckErr(err)
+	if b.Address != nil && !b.Put {
+		addr, err := url.Parse(b.Address.URL)
+		if err != nil {
+			return fmt.Errorf("parsing upload URL: %w", err)
+		}
+		f, err := openBlob(b, u.verify)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		progress := newProgressReader(f, func(n uint64) {
+			u.reporter.report(event{Type: eventUploading, Blob: digestutil.Format(digest), Size: b.Size, Bytes: n, URL: b.Address.URL})
+		})
+		err = u.client.PutBlob(ctx, client.Address{URL: addr, Headers: b.Address.Headers}, progress, b.Size)
+		if err != nil {
+			return err
+		}
 
-	accOut, err := result.MapResultR1(
-		accRcpt.Out(),
-		func(o ipld.Any) (blob.AcceptOK, error) {
-			model := blob.AcceptOK{}
-			err = datamodel.Rebind(datamodel.NewAny(o), &model)
-			return model, err
-		},
-		func(x ipld.Any) (error, error) {
-			return nil, fmt.Errorf("failed %q task: %+v", blob.AcceptCommand, x)
-		},
-	)
-	cobra.CheckErr(err)
-	accOK, _ := result.Unwrap(accOut)
+		u.reporter.report(event{Type: eventUploading, Blob: digestutil.Format(digest), Size: b.Size, Bytes: b.Size, URL: b.Address.URL})
+		b.Put = true
+		if err := save(); err != nil {
+			return err
+		}
+	}
 
-	cmd.Printf("✍️ location commitment: %s\n", accOK.Site)
+	if b.Address != nil && !b.Concluded {
+		put, err := invocation.Decode(b.PutInvocation)
+		if err != nil {
+			return fmt.Errorf("decoding put invocation: %w", err)
+		}
+		_, err = u.client.ConcludePut(ctx, space, digest, b.Size, put)
+		if err != nil {
+			return err
+		}
 
-	var locationCommitment ucan.Invocation
-	for _, inv := range accRcptCt.Invocations() {
-		if inv.Link() == accOK.Site {
-			locationCommitment = inv
+		u.reporter.report(event{Type: eventConcluded, Blob: digestutil.Format(digest), Task: put.Task().Link().String()})
+		b.Concluded = true
+		if err := save(); err != nil {
+			return err
 		}
 	}
-	if locationCommitment == nil {
-		return fmt.Errorf("missing location commitment\n")
-	}
 
-	loc := assert_caps.LocationArguments{}
-	err = datamodel.Rebind(datamodel.NewAny(locationCommitment.Arguments()), &loc)
-	cobra.CheckErr(err)
+	if !b.Accepted {
+		acc, err := u.client.AwaitAccept(ctx, *b.SiteTask)
+		if err != nil {
+			return err
+		}
 
-	for _, location := range loc.Location {
-		cmd.Printf("📍 blob location: %s\n", location.URL().String())
+		b.Locations = nil
+		for _, location := range acc.Locations {
+			b.Locations = append(b.Locations, location.String())
+		}
+		b.Accepted = true
+		if err := save(); err != nil {
+			return err
+		}
+		u.reporter.report(event{Type: eventAccepted, Blob: digestutil.Format(digest), Task: b.SiteTask.String(), Commitment: acc.Commitment.Link().String()})
 	}
 
-	cmd.Printf("✅ upload complete! Blob %q accepted in space %q\n", digestutil.Format(digest), space)
-	cmd.Printf("🌱 %s\n", cid.NewCidV1(cid.Raw, digest))
+	for _, location := range b.Locations {
+		u.reporter.report(event{Type: eventLocation, Blob: digestutil.Format(digest), URL: location})
+	}
 
 	return nil
 }
-
-// extractBlobProviderKey extracts the blob provider's signing key from the
-// /http/put invocation metadata.
-func extractBlobProviderKey(inv ucan.Invocation) (principal.Signer, error) {
-	if _, ok := inv.Metadata()["keys"]; !ok {
-		return nil, fmt.Errorf("missing 'keys' metadata")
-	}
-	keyMap, ok := inv.Metadata()["keys"].(ipld.Map)
-	if !ok {
-		return nil, fmt.Errorf("invalid 'keys' metadata: not an IPLD map")
-	}
-	val, ok := keyMap[inv.Issuer().DID().String()]
-	if !ok {
-		return nil, fmt.Errorf("missing private key for %q in 'keys' metadata", inv.Issuer().DID().String())
-	}
-	keyBytes, ok := val.([]byte)
-	if !ok {
-		return nil, fmt.Errorf("invalid private key for %q in 'keys' metadata: not a byte slice", inv.Issuer().DID().String())
-	}
-	return ed25519.Decode(keyBytes)
-}
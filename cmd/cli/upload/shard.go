@@ -0,0 +1,143 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/alanshaw/buff/pkg/blobindex"
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/multiformats/go-multihash"
+)
+
+// defaultShardSize is the default maximum size of a CAR shard. It is a little
+// under half the maximum blob size accepted by /blob/add.
+const defaultShardSize = 133_169_152
+
+// shard is a CAR file containing a subset of the blocks of a DAG.
+type shard struct {
+	path   string
+	digest multihash.Multihash
+	size   uint64
+	// slices are the positions of the blocks within the shard.
+	slices []blobindex.Slice
+}
+
+// sharder writes blocks to CAR files in a directory, starting a new CAR
+// whenever the current one would exceed the maximum shard size. Completed
+// shards are hashed in the background while the next shard is written.
+type sharder struct {
+	dir     string
+	maxSize uint64
+	shards  []*shard
+	file    *os.File
+	writer  *car.Writer
+	slices  []blobindex.Slice
+
+	hashing sync.WaitGroup
+	sem     chan struct{}
+	mu      sync.Mutex
+	errs    []error
+}
+
+func newSharder(dir string, maxSize uint64, concurrency int) (*sharder, error) {
+	hdr, err := car.EncodeHeader(nil)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize <= uint64(len(hdr)) {
+		return nil, fmt.Errorf("shard size too small: %d", maxSize)
+	}
+	return &sharder{dir: dir, maxSize: maxSize, sem: make(chan struct{}, max(concurrency, 1))}, nil
+}
+
+func (s *sharder) put(blk ipld.Block) error {
+	if s.writer != nil && s.writer.Size()+car.BlockSize(blk) > s.maxSize {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	if s.writer == nil {
+		f, err := os.CreateTemp(s.dir, "shard-*.car")
+		if err != nil {
+			return fmt.Errorf("creating shard file: %w", err)
+		}
+		w, err := car.NewWriter(f, nil)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return fmt.Errorf("creating shard writer: %w", err)
+		}
+		s.file, s.writer = f, w
+		if s.writer.Size()+car.BlockSize(blk) > s.maxSize {
+			return fmt.Errorf("block %s is too big to fit in a shard of size %d", blk.Link(), s.maxSize)
+		}
+	}
+	pos, err := s.writer.Put(blk)
+	if err != nil {
+		return err
+	}
+	s.slices = append(s.slices, blobindex.Slice{
+		Digest:   blk.Link().Hash(),
+		Position: blobindex.Position{Offset: pos.Offset, Length: pos.Length},
+	})
+	return nil
+}
+
+// flush completes the current shard, calculating its digest in the background.
+// The shard file is closed once it has been hashed, so that only the files of
+// shards being written or hashed are open at any time.
+func (s *sharder) flush() error {
+	f := s.file
+	sh := &shard{path: f.Name(), slices: s.slices}
+	s.shards = append(s.shards, sh)
+	s.file, s.writer, s.slices = nil, nil, nil
+
+	s.sem <- struct{}{}
+	s.hashing.Add(1)
+	go func() {
+		defer s.hashing.Done()
+		defer func() { <-s.sem }()
+		digest, size, err := hashFile(f)
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("closing shard file: %w", cerr)
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.errs = append(s.errs, fmt.Errorf("hashing shard %s: %w", sh.path, err))
+			s.mu.Unlock()
+			return
+		}
+		sh.digest, sh.size = digest, size
+	}()
+	return nil
+}
+
+// close completes the current shard, waits for all shards to be hashed and
+// returns the shards written.
+func (s *sharder) close() ([]*shard, error) {
+	if s.writer != nil {
+		if err := s.flush(); err != nil {
+			return nil, err
+		}
+	}
+	s.hashing.Wait()
+	if err := errors.Join(s.errs...); err != nil {
+		return nil, err
+	}
+	return s.shards, nil
+}
+
+// cleanup removes all shard files from disk.
+func (s *sharder) cleanup() {
+	s.hashing.Wait()
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+	}
+	for _, sh := range s.shards {
+		os.Remove(sh.path)
+	}
+}
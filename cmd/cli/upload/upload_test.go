@@ -0,0 +1,178 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/alanshaw/buff/pkg/client"
+	"github.com/alanshaw/buff/pkg/store"
+	dlgstore "github.com/alanshaw/buff/pkg/store/delegation"
+	upstore "github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/alanshaw/buff/pkg/testutil"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/multiformats/go-multihash"
+	"github.com/spf13/cobra"
+)
+
+// newTestUploader creates an uploader for a new space against a fake upload
+// service.
+func newTestUploader(t *testing.T, svc *testutil.UploadService) (*uploader, did.DID) {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	space, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dlg, err := delegation.Delegate(space, id.DID(), space, command.Top(), delegation.WithNoExpiration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegations := dlgstore.NewDSDelegationStore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	if err := delegations.Put(ctx, dlg); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.New(id, svc.Config(), delegations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &uploader{
+		client:      c,
+		uploadStore: upstore.NewDSUploadStore(dssync.MutexWrap(datastore.NewMapDatastore())),
+		reporter:    newTextReporter(io.Discard),
+		concurrency: 2,
+		verify:      true,
+	}, space.DID()
+}
+
+func writeRandomFile(t *testing.T, path string, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// assertUploaded checks that the upload was completed, and that the service
+// has the blobs and the shards of the upload.
+func assertUploaded(t *testing.T, svc *testutil.UploadService, u *uploader, upload upstore.Upload) {
+	t.Helper()
+
+	for _, b := range upload.Blobs {
+		data, ok := svc.Blob(b.Digest)
+		if !ok {
+			t.Fatalf("service does not have blob %s", digestutil.Format(b.Digest))
+		}
+		if uint64(len(data)) != b.Size {
+			t.Fatalf("blob %s has size %d, expected %d", digestutil.Format(b.Digest), len(data), b.Size)
+		}
+		if _, err := os.Stat(b.Path); b.Staged && !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("staged file %s was not removed", b.Path)
+		}
+	}
+
+	shards, ok := svc.Upload(upload.Space, upload.Root)
+	if !ok {
+		t.Fatalf("upload %s was not registered", upload.Root)
+	}
+	expected := shardLinks(&upload)
+	if len(shards) != len(expected) {
+		t.Fatalf("upload has %d shards, expected %d", len(shards), len(expected))
+	}
+	for i := range shards {
+		if shards[i].String() != expected[i].String() {
+			t.Fatalf("shard %d is %s, expected %s", i, shards[i], expected[i])
+		}
+	}
+
+	_, err := u.uploadStore.Get(context.Background(), upload.Space, upload.Root)
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("upload journal was not removed: %v", err)
+	}
+}
+
+func TestUploadBlob(t *testing.T) {
+	svc := testutil.NewUploadService(t)
+	u, space := newTestUploader(t, svc)
+
+	path := filepath.Join(t.TempDir(), "file")
+	data := writeRandomFile(t, path, 1<<20)
+
+	upload, err := stageBlob(&cobra.Command{}, t.TempDir(), space, []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.run(context.Background(), &upload); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUploaded(t, svc, u, upload)
+
+	digest, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := svc.Blob(digest)
+	if !bytes.Equal(stored, data) {
+		t.Fatal("stored blob does not match the file")
+	}
+	if upload.Root.String() != cid.NewCidV1(cid.Raw, digest).String() {
+		t.Fatalf("root is %s, expected a raw CID of the file", upload.Root)
+	}
+	if len(svc.Indexes(space)) != 0 {
+		t.Fatal("an index was added for a single blob upload")
+	}
+}
+
+func TestUploadDAG(t *testing.T) {
+	svc := testutil.NewUploadService(t)
+	u, space := newTestUploader(t, svc)
+
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeRandomFile(t, filepath.Join(dir, name), 3<<19)
+	}
+
+	upload, err := stageDAG(t.TempDir(), space, []string{dir}, 2<<20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upload.Blobs) < 2 {
+		t.Fatalf("expected multiple shards, got %d", len(upload.Blobs))
+	}
+	if err := u.run(context.Background(), &upload); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUploaded(t, svc, u, upload)
+
+	if _, ok := svc.Blob(upload.Index.Digest); !ok {
+		t.Fatal("service does not have the index blob")
+	}
+	index := cid.NewCidV1(car.Codec, upload.Index.Digest)
+	indexes := svc.Indexes(space)
+	if len(indexes) != 1 || indexes[0].String() != index.String() {
+		t.Fatalf("indexes are %v, expected [%s]", indexes, index)
+	}
+}
@@ -0,0 +1,197 @@
+package blobindex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// Extract decodes an index from an archive (CAR) created by
+// [ShardedDAGIndex.Archive]. Blocks are verified against their CIDs.
+func Extract(r io.Reader) (*ShardedDAGIndex, error) {
+	cr, err := car.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(cr.Roots()) != 1 {
+		return nil, fmt.Errorf("expected 1 root, got %d", len(cr.Roots()))
+	}
+
+	blocks := map[cid.Cid][]byte{}
+	for {
+		blk, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading block: %w", err)
+		}
+		if err := verifyBlock(blk.Link(), blk.Bytes()); err != nil {
+			return nil, err
+		}
+		blocks[blk.Link()] = blk.Bytes()
+	}
+
+	root, ok := blocks[cr.Roots()[0]]
+	if !ok {
+		return nil, fmt.Errorf("missing root block: %s", cr.Roots()[0])
+	}
+	content, shardLinks, err := decodeRoot(root)
+	if err != nil {
+		return nil, fmt.Errorf("decoding index root: %w", err)
+	}
+
+	idx := New(content)
+	for _, l := range shardLinks {
+		b, ok := blocks[l]
+		if !ok {
+			return nil, fmt.Errorf("missing shard block: %s", l)
+		}
+		s, err := decodeShard(b)
+		if err != nil {
+			return nil, fmt.Errorf("decoding shard %s: %w", l, err)
+		}
+		idx.Shards = append(idx.Shards, s)
+	}
+	return idx, nil
+}
+
+func verifyBlock(c cid.Cid, b []byte) error {
+	prefix := c.Prefix()
+	digest, err := multihash.Sum(b, prefix.MhType, prefix.MhLength)
+	if err != nil {
+		return fmt.Errorf("hashing block %s: %w", c, err)
+	}
+	if !bytes.Equal(digest, c.Hash()) {
+		return fmt.Errorf("block %s does not match its CID", c)
+	}
+	return nil
+}
+
+func decodeRoot(b []byte) (cid.Cid, []cid.Cid, error) {
+	cr := cbg.NewCborReader(bytes.NewReader(b))
+	if err := expectHeader(cr, cbg.MajMap, 1); err != nil {
+		return cid.Undef, nil, err
+	}
+	version, err := cbg.ReadString(cr)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	if version != ShardedDAGIndexVersion {
+		return cid.Undef, nil, fmt.Errorf("unsupported index version: %q", version)
+	}
+	maj, n, err := cr.ReadHeader()
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	if maj != cbg.MajMap {
+		return cid.Undef, nil, errors.New("index is not a map")
+	}
+	var (
+		content cid.Cid
+		shards  []cid.Cid
+	)
+	for range n {
+		key, err := cbg.ReadString(cr)
+		if err != nil {
+			return cid.Undef, nil, err
+		}
+		switch key {
+		case "content":
+			content, err = cbg.ReadCid(cr)
+			if err != nil {
+				return cid.Undef, nil, fmt.Errorf("reading content: %w", err)
+			}
+		case "shards":
+			maj, l, err := cr.ReadHeader()
+			if err != nil {
+				return cid.Undef, nil, err
+			}
+			if maj != cbg.MajArray {
+				return cid.Undef, nil, errors.New("shards is not an array")
+			}
+			for range l {
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return cid.Undef, nil, fmt.Errorf("reading shard link: %w", err)
+				}
+				shards = append(shards, c)
+			}
+		default:
+			return cid.Undef, nil, fmt.Errorf("unexpected index field: %q", key)
+		}
+	}
+	if !content.Defined() {
+		return cid.Undef, nil, errors.New("missing content")
+	}
+	return content, shards, nil
+}
+
+func decodeShard(b []byte) (Shard, error) {
+	cr := cbg.NewCborReader(bytes.NewReader(b))
+	if err := expectHeader(cr, cbg.MajArray, 2); err != nil {
+		return Shard{}, err
+	}
+	digest, err := readDigest(cr)
+	if err != nil {
+		return Shard{}, fmt.Errorf("reading shard digest: %w", err)
+	}
+	maj, n, err := cr.ReadHeader()
+	if err != nil {
+		return Shard{}, err
+	}
+	if maj != cbg.MajArray {
+		return Shard{}, errors.New("slices is not an array")
+	}
+	s := Shard{Digest: digest}
+	for range n {
+		if err := expectHeader(cr, cbg.MajArray, 2); err != nil {
+			return Shard{}, err
+		}
+		slc, err := readDigest(cr)
+		if err != nil {
+			return Shard{}, fmt.Errorf("reading slice digest: %w", err)
+		}
+		if err := expectHeader(cr, cbg.MajArray, 2); err != nil {
+			return Shard{}, err
+		}
+		var pos [2]uint64
+		for i := range pos {
+			maj, v, err := cr.ReadHeader()
+			if err != nil {
+				return Shard{}, err
+			}
+			if maj != cbg.MajUnsignedInt {
+				return Shard{}, errors.New("position is not an integer")
+			}
+			pos[i] = v
+		}
+		s.Slices = append(s.Slices, Slice{Digest: slc, Position: Position{Offset: pos[0], Length: pos[1]}})
+	}
+	return s, nil
+}
+
+func expectHeader(cr *cbg.CborReader, maj byte, n uint64) error {
+	m, l, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	if m != maj || l != n {
+		return fmt.Errorf("unexpected CBOR header: major type %d, length %d", m, l)
+	}
+	return nil
+}
+
+func readDigest(cr *cbg.CborReader) (multihash.Multihash, error) {
+	b, err := cbg.ReadByteArray(cr, cbg.ByteArrayMaxLen)
+	if err != nil {
+		return nil, err
+	}
+	return multihash.Cast(b)
+}
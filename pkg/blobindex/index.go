@@ -0,0 +1,222 @@
+// Package blobindex implements the sharded DAG index, which records the byte
+// ranges of the blocks of a DAG within the shards (blobs) that contain them.
+package blobindex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/alanshaw/buff/pkg/car"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// ShardedDAGIndexVersion is the key used in the root block of an archived
+// index to identify its version.
+const ShardedDAGIndexVersion = "index/sharded/dag@0.1"
+
+// Position is a byte range within a shard.
+type Position struct {
+	Offset uint64
+	Length uint64
+}
+
+// Slice is the position of a block within a shard.
+type Slice struct {
+	Digest   multihash.Multihash
+	Position Position
+}
+
+// Shard is a blob that contains blocks of a DAG.
+type Shard struct {
+	Digest multihash.Multihash
+	Slices []Slice
+}
+
+// ShardedDAGIndex describes where the blocks of a DAG can be found within a
+// set of shards.
+type ShardedDAGIndex struct {
+	Content ucan.Link
+	Shards  []Shard
+}
+
+// New creates a new, empty index for the passed content root.
+func New(content ucan.Link) *ShardedDAGIndex {
+	return &ShardedDAGIndex{Content: content}
+}
+
+// SetSlice records the position of a block within a shard.
+func (idx *ShardedDAGIndex) SetSlice(shard multihash.Multihash, slice multihash.Multihash, pos Position) {
+	for i, s := range idx.Shards {
+		if bytes.Equal(s.Digest, shard) {
+			idx.Shards[i].Slices = append(idx.Shards[i].Slices, Slice{slice, pos})
+			return
+		}
+	}
+	idx.Shards = append(idx.Shards, Shard{Digest: shard, Slices: []Slice{{slice, pos}}})
+}
+
+// ShardsContaining returns the digests of the shards that contain the block
+// with the passed digest.
+func (idx *ShardedDAGIndex) ShardsContaining(block multihash.Multihash) []multihash.Multihash {
+	var shards []multihash.Multihash
+	for _, s := range idx.Shards {
+		for _, slc := range s.Slices {
+			if bytes.Equal(slc.Digest, block) {
+				shards = append(shards, s.Digest)
+				break
+			}
+		}
+	}
+	return shards
+}
+
+// Archive encodes the index as a CAR file, written to w. Shards and slices are
+// sorted by digest so the same index always produces the same archive.
+func (idx *ShardedDAGIndex) Archive(w io.Writer) error {
+	shards := slices.Clone(idx.Shards)
+	slices.SortFunc(shards, func(a, b Shard) int { return bytes.Compare(a.Digest, b.Digest) })
+
+	var shardBlocks []block
+	for _, s := range shards {
+		blk, err := encodeShard(s)
+		if err != nil {
+			return fmt.Errorf("encoding shard %s: %w", s.Digest.B58String(), err)
+		}
+		shardBlocks = append(shardBlocks, blk)
+	}
+
+	root, err := encodeRoot(idx.Content, shardBlocks)
+	if err != nil {
+		return fmt.Errorf("encoding index root: %w", err)
+	}
+
+	cw, err := car.NewWriter(w, []cid.Cid{root.Link()})
+	if err != nil {
+		return err
+	}
+	if _, err := cw.Put(root); err != nil {
+		return err
+	}
+	for _, blk := range shardBlocks {
+		if _, err := cw.Put(blk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type block struct {
+	cid   cid.Cid
+	bytes []byte
+}
+
+func (b block) Link() cid.Cid {
+	return b.cid
+}
+
+func (b block) Bytes() []byte {
+	return b.bytes
+}
+
+func newBlock(b []byte) (block, error) {
+	digest, err := multihash.Sum(b, multihash.SHA2_256, -1)
+	if err != nil {
+		return block{}, err
+	}
+	return block{cid: cid.NewCidV1(cid.DagCBOR, digest), bytes: b}, nil
+}
+
+// encodeShard encodes a shard as dag-cbor:
+//
+//	[shard digest, [[slice digest, [offset, length]], ...]]
+func encodeShard(s Shard) (block, error) {
+	slcs := slices.Clone(s.Slices)
+	slices.SortFunc(slcs, func(a, b Slice) int { return bytes.Compare(a.Digest, b.Digest) })
+
+	var buf bytes.Buffer
+	cw := cbg.NewCborWriter(&buf)
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, 2); err != nil {
+		return block{}, err
+	}
+	if err := writeBytes(cw, s.Digest); err != nil {
+		return block{}, err
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(slcs))); err != nil {
+		return block{}, err
+	}
+	for _, slc := range slcs {
+		if err := cw.WriteMajorTypeHeader(cbg.MajArray, 2); err != nil {
+			return block{}, err
+		}
+		if err := writeBytes(cw, slc.Digest); err != nil {
+			return block{}, err
+		}
+		if err := cw.WriteMajorTypeHeader(cbg.MajArray, 2); err != nil {
+			return block{}, err
+		}
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, slc.Position.Offset); err != nil {
+			return block{}, err
+		}
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, slc.Position.Length); err != nil {
+			return block{}, err
+		}
+	}
+	return newBlock(buf.Bytes())
+}
+
+// encodeRoot encodes the index root as dag-cbor:
+//
+//	{"index/sharded/dag@0.1": {"shards": [shard links...], "content": link}}
+func encodeRoot(content ucan.Link, shards []block) (block, error) {
+	var buf bytes.Buffer
+	cw := cbg.NewCborWriter(&buf)
+	if err := cw.WriteMajorTypeHeader(cbg.MajMap, 1); err != nil {
+		return block{}, err
+	}
+	if err := writeString(cw, ShardedDAGIndexVersion); err != nil {
+		return block{}, err
+	}
+	// map keys in dag-cbor canonical order (length first)
+	if err := cw.WriteMajorTypeHeader(cbg.MajMap, 2); err != nil {
+		return block{}, err
+	}
+	if err := writeString(cw, "shards"); err != nil {
+		return block{}, err
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(shards))); err != nil {
+		return block{}, err
+	}
+	for _, s := range shards {
+		if err := cbg.WriteCid(cw, s.Link()); err != nil {
+			return block{}, err
+		}
+	}
+	if err := writeString(cw, "content"); err != nil {
+		return block{}, err
+	}
+	if err := cbg.WriteCid(cw, content); err != nil {
+		return block{}, err
+	}
+	return newBlock(buf.Bytes())
+}
+
+func writeString(cw *cbg.CborWriter, s string) error {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := cw.WriteString(s)
+	return err
+}
+
+func writeBytes(cw *cbg.CborWriter, b []byte) error {
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := cw.Write(b)
+	return err
+}
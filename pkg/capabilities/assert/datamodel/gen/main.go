@@ -0,0 +1,14 @@
+package main
+
+import (
+	adm "github.com/alanshaw/buff/pkg/capabilities/assert/datamodel"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func main() {
+	if err := cbg.WriteMapEncodersToFile("../cbor_gen.go", "datamodel",
+		adm.IndexArgumentsModel{},
+	); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,12 @@
+package datamodel
+
+import (
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+type IndexArgumentsModel struct {
+	// Content is the root of the DAG the index describes.
+	Content ucan.Link `cborgen:"content"`
+	// Index is the link to a CAR containing a sharded DAG index.
+	Index ucan.Link `cborgen:"index"`
+}
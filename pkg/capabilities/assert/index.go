@@ -0,0 +1,12 @@
+package assert
+
+import (
+	adm "github.com/alanshaw/buff/pkg/capabilities/assert/datamodel"
+	"github.com/alanshaw/ucantone/validator/bindcap"
+)
+
+type IndexArguments = adm.IndexArgumentsModel
+
+const IndexCommand = "/assert/index"
+
+var Index, _ = bindcap.New[*IndexArguments](IndexCommand)
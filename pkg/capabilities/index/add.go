@@ -0,0 +1,16 @@
+package index
+
+import (
+	idm "github.com/alanshaw/buff/pkg/capabilities/index/datamodel"
+	cdm "github.com/alanshaw/libracha/capabilities/datamodel"
+	"github.com/alanshaw/ucantone/validator/bindcap"
+)
+
+const AddCommand = "/index/add"
+
+type (
+	AddArguments = idm.AddArgumentsModel
+	AddOK        = cdm.UnitModel
+)
+
+var Add, _ = bindcap.New[*AddArguments](AddCommand)
@@ -0,0 +1,10 @@
+package datamodel
+
+import (
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+type AddArgumentsModel struct {
+	// Index is the link to a CAR containing a sharded DAG index.
+	Index ucan.Link `cborgen:"index"`
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	idm "github.com/alanshaw/buff/pkg/capabilities/index/datamodel"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func main() {
+	if err := cbg.WriteMapEncodersToFile("../cbor_gen.go", "datamodel",
+		idm.AddArgumentsModel{},
+	); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,192 @@
+// Code generated by github.com/whyrusleeping/cbor-gen. DO NOT EDIT.
+
+package datamodel
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	cid "github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	xerrors "golang.org/x/xerrors"
+)
+
+var _ = xerrors.Errorf
+var _ = cid.Undef
+var _ = math.E
+var _ = sort.Sort
+
+func (t *RevokeArgumentsModel) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+	fieldCount := 2
+
+	if t.Proof == nil {
+		fieldCount--
+	}
+
+	if _, err := cw.Write(cbg.CborEncodeMajorType(cbg.MajMap, uint64(fieldCount))); err != nil {
+		return err
+	}
+
+	// t.UCAN (cid.Cid) (struct)
+	if len("ucan") > 8192 {
+		return xerrors.Errorf("Value in field \"ucan\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("ucan"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("ucan")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteCid(cw, t.UCAN); err != nil {
+		return xerrors.Errorf("failed to write cid field t.UCAN: %w", err)
+	}
+
+	// t.Proof ([]cid.Cid) (slice)
+	if t.Proof != nil {
+
+		if len("proof") > 8192 {
+			return xerrors.Errorf("Value in field \"proof\" was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("proof"))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string("proof")); err != nil {
+			return err
+		}
+
+		if len(t.Proof) > 8192 {
+			return xerrors.Errorf("Slice value in field t.Proof was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Proof))); err != nil {
+			return err
+		}
+		for _, v := range t.Proof {
+
+			if err := cbg.WriteCid(cw, v); err != nil {
+				return xerrors.Errorf("failed to write cid field v: %w", err)
+			}
+
+		}
+	}
+	return nil
+}
+
+func (t *RevokeArgumentsModel) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = RevokeArgumentsModel{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("RevokeArgumentsModel: map struct too large (%d)", extra)
+	}
+
+	n := extra
+
+	nameBuf := make([]byte, 5)
+	for i := uint64(0); i < n; i++ {
+		nameLen, ok, err := cbg.ReadFullStringIntoBuf(cr, nameBuf, 8192)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(cr, func(cid.Cid) {}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch string(nameBuf[:nameLen]) {
+		// t.UCAN (cid.Cid) (struct)
+		case "ucan":
+
+			{
+
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return xerrors.Errorf("failed to read cid field t.UCAN: %w", err)
+				}
+
+				t.UCAN = c
+
+			}
+			// t.Proof ([]cid.Cid) (slice)
+		case "proof":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > 8192 {
+				return fmt.Errorf("t.Proof: array too large (%d)", extra)
+			}
+
+			if maj != cbg.MajArray {
+				return fmt.Errorf("expected cbor array")
+			}
+
+			if extra > 0 {
+				t.Proof = make([]cid.Cid, extra)
+			}
+
+			for i := 0; i < int(extra); i++ {
+				{
+					var maj byte
+					var extra uint64
+					var err error
+					_ = maj
+					_ = extra
+					_ = err
+
+					{
+
+						c, err := cbg.ReadCid(cr)
+						if err != nil {
+							return xerrors.Errorf("failed to read cid field t.Proof[i]: %w", err)
+						}
+
+						t.Proof[i] = c
+
+					}
+
+				}
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(r, func(cid.Cid) {}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	udm "github.com/alanshaw/buff/pkg/capabilities/ucan/datamodel"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func main() {
+	if err := cbg.WriteMapEncodersToFile("../cbor_gen.go", "datamodel",
+		udm.RevokeArgumentsModel{},
+	); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,18 @@
+package datamodel
+
+import (
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// RevokeArgumentsModel are the arguments of a /ucan/revoke invocation, in the
+// shape of the Storacha "ucan/revoke" capability. The subject of the invocation
+// is the principal authorizing the revocation, which must be the issuer of the
+// revoked delegation or of one of the delegations in its proof.
+type RevokeArgumentsModel struct {
+	// UCAN is the link to the delegation being revoked.
+	UCAN ucan.Link `cborgen:"ucan"`
+	// Proof is the proof chain from the revoked delegation to the delegation
+	// issued by the subject. It is omitted if the subject issued the revoked
+	// delegation.
+	Proof []ucan.Link `cborgen:"proof,omitempty"`
+}
@@ -0,0 +1,16 @@
+package ucan
+
+import (
+	udm "github.com/alanshaw/buff/pkg/capabilities/ucan/datamodel"
+	cdm "github.com/alanshaw/libracha/capabilities/datamodel"
+	"github.com/alanshaw/ucantone/validator/bindcap"
+)
+
+const RevokeCommand = "/ucan/revoke"
+
+type (
+	RevokeArguments = udm.RevokeArgumentsModel
+	RevokeOK        = cdm.UnitModel
+)
+
+var Revoke, _ = bindcap.New[*RevokeArguments](RevokeCommand)
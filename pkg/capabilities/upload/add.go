@@ -0,0 +1,15 @@
+package upload
+
+import (
+	udm "github.com/alanshaw/buff/pkg/capabilities/upload/datamodel"
+	"github.com/alanshaw/ucantone/validator/bindcap"
+)
+
+const AddCommand = "/upload/add"
+
+type (
+	AddArguments = udm.AddArgumentsModel
+	AddOK        = udm.AddOKModel
+)
+
+var Add, _ = bindcap.New[*AddArguments](AddCommand)
@@ -0,0 +1,15 @@
+package datamodel
+
+import (
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+type AddArgumentsModel struct {
+	Root   ucan.Link   `cborgen:"root"`
+	Shards []ucan.Link `cborgen:"shards"`
+}
+
+type AddOKModel struct {
+	Root   ucan.Link   `cborgen:"root"`
+	Shards []ucan.Link `cborgen:"shards"`
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	udm "github.com/alanshaw/buff/pkg/capabilities/upload/datamodel"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func main() {
+	if err := cbg.WriteMapEncodersToFile("../cbor_gen.go", "datamodel",
+		udm.AddArgumentsModel{},
+		udm.AddOKModel{},
+	); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package car implements reading and writing of CARv1 (Content Addressable
+// aRchive) files.
+package car
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+const (
+	// ContentType is the media type for CAR files.
+	ContentType = "application/vnd.ipld.car"
+	// Codec is the multicodec code for CAR files.
+	Codec = 0x0202
+)
+
+// Position is the location of a block's data within a CAR file.
+type Position struct {
+	// Offset is the byte offset of the block data from the start of the CAR.
+	Offset uint64
+	// Length is the length of the block data in bytes.
+	Length uint64
+}
+
+// EncodeHeader encodes a CARv1 header with the passed roots, including the
+// length prefix.
+func EncodeHeader(roots []cid.Cid) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := cbg.NewCborWriter(&buf)
+	// map keys sorted in dag-cbor canonical order (length first)
+	if err := cw.WriteMajorTypeHeader(cbg.MajMap, 2); err != nil {
+		return nil, err
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("roots"))); err != nil {
+		return nil, err
+	}
+	if _, err := cw.WriteString("roots"); err != nil {
+		return nil, err
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(roots))); err != nil {
+		return nil, err
+	}
+	for _, r := range roots {
+		if err := cbg.WriteCid(cw, r); err != nil {
+			return nil, fmt.Errorf("writing root: %w", err)
+		}
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("version"))); err != nil {
+		return nil, err
+	}
+	if _, err := cw.WriteString("version"); err != nil {
+		return nil, err
+	}
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, 1); err != nil {
+		return nil, err
+	}
+	return append(binary.AppendUvarint(nil, uint64(buf.Len())), buf.Bytes()...), nil
+}
+
+// BlockSize returns the number of bytes the block will occupy in a CAR file,
+// including the length prefix and CID.
+func BlockSize(blk ipld.Block) uint64 {
+	n := uint64(len(blk.Link().Bytes()) + len(blk.Bytes()))
+	return uint64(len(binary.AppendUvarint(nil, n))) + n
+}
+
+// Writer writes blocks to an underlying writer in CARv1 format.
+type Writer struct {
+	w      io.Writer
+	offset uint64
+}
+
+// NewWriter creates a new CAR writer, writing the header immediately.
+func NewWriter(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	hdr, err := EncodeHeader(roots)
+	if err != nil {
+		return nil, fmt.Errorf("encoding header: %w", err)
+	}
+	if _, err := w.Write(hdr); err != nil {
+		return nil, fmt.Errorf("writing header: %w", err)
+	}
+	return &Writer{w: w, offset: uint64(len(hdr))}, nil
+}
+
+// Put writes a block to the CAR, returning the position of the block data.
+func (cw *Writer) Put(blk ipld.Block) (Position, error) {
+	c := blk.Link().Bytes()
+	data := blk.Bytes()
+	prefix := binary.AppendUvarint(nil, uint64(len(c)+len(data)))
+	for _, b := range [][]byte{prefix, c, data} {
+		if _, err := cw.w.Write(b); err != nil {
+			return Position{}, fmt.Errorf("writing block %s: %w", blk.Link(), err)
+		}
+	}
+	pos := Position{
+		Offset: cw.offset + uint64(len(prefix)+len(c)),
+		Length: uint64(len(data)),
+	}
+	cw.offset += uint64(len(prefix) + len(c) + len(data))
+	return pos, nil
+}
+
+// Size returns the number of bytes written so far.
+func (cw *Writer) Size() uint64 {
+	return cw.offset
+}
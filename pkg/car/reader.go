@@ -0,0 +1,141 @@
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// maxSectionSize is the maximum size of a header or block section that will
+// be read.
+const maxSectionSize = 32 << 20
+
+type block struct {
+	cid   cid.Cid
+	bytes []byte
+}
+
+func (b block) Link() cid.Cid {
+	return b.cid
+}
+
+func (b block) Bytes() []byte {
+	return b.bytes
+}
+
+// Reader reads blocks from a CARv1 file.
+type Reader struct {
+	r     *bufio.Reader
+	roots []cid.Cid
+}
+
+// NewReader creates a new CAR reader, reading the header immediately.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	hdr, err := readSection(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	roots, err := decodeHeader(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("decoding header: %w", err)
+	}
+	return &Reader{r: br, roots: roots}, nil
+}
+
+// Roots returns the roots listed in the CAR header.
+func (cr *Reader) Roots() []cid.Cid {
+	return cr.roots
+}
+
+// Next returns the next block in the CAR. It returns [io.EOF] when there are
+// no more blocks. Block data is NOT verified against the CID.
+func (cr *Reader) Next() (ipld.Block, error) {
+	section, err := readSection(cr.r)
+	if err != nil {
+		return nil, err
+	}
+	n, c, err := cid.CidFromBytes(section)
+	if err != nil {
+		return nil, fmt.Errorf("decoding block CID: %w", err)
+	}
+	return block{cid: c, bytes: section[n:]}, nil
+}
+
+func readSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading section length: %w", err)
+	}
+	if size == 0 || size > maxSectionSize {
+		return nil, fmt.Errorf("invalid section length: %d", size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("reading section: %w", err)
+	}
+	return b, nil
+}
+
+func decodeHeader(b []byte) ([]cid.Cid, error) {
+	cr := cbg.NewCborReader(bytes.NewReader(b))
+	maj, n, err := cr.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajMap {
+		return nil, errors.New("header is not a map")
+	}
+	var (
+		roots   []cid.Cid
+		version uint64
+	)
+	for range n {
+		key, err := cbg.ReadString(cr)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "roots":
+			maj, l, err := cr.ReadHeader()
+			if err != nil {
+				return nil, err
+			}
+			if maj != cbg.MajArray {
+				return nil, errors.New("roots is not an array")
+			}
+			for range l {
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return nil, fmt.Errorf("reading root: %w", err)
+				}
+				roots = append(roots, c)
+			}
+		case "version":
+			maj, v, err := cr.ReadHeader()
+			if err != nil {
+				return nil, err
+			}
+			if maj != cbg.MajUnsignedInt {
+				return nil, errors.New("version is not an integer")
+			}
+			version = v
+		default:
+			return nil, fmt.Errorf("unexpected header field: %q", key)
+		}
+	}
+	if version != 1 {
+		return nil, fmt.Errorf("unsupported CAR version: %d", version)
+	}
+	return roots, nil
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	assert_caps "github.com/alanshaw/libracha/capabilities/assert"
+	"github.com/alanshaw/libracha/capabilities/blob"
+	http_caps "github.com/alanshaw/libracha/capabilities/http"
+	ucan_caps "github.com/alanshaw/libracha/capabilities/ucan"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/execution"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/result"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/alanshaw/ucantone/ucan/delegation/policy"
+	"github.com/alanshaw/ucantone/ucan/invocation"
+	"github.com/alanshaw/ucantone/ucan/receipt"
+	"github.com/multiformats/go-multihash"
+)
+
+// Address is where blob data should be uploaded to.
+type Address struct {
+	URL     *url.URL
+	Headers map[string]string
+}
+
+// Allocation is the result of adding a blob to a space.
+type Allocation struct {
+	// Site is the /blob/accept task that will produce a location commitment for
+	// the blob once it has been accepted.
+	Site ucan.Link
+	// Provider is the storage provider that allocated space for the blob.
+	Provider did.DID
+	// Put is the /http/put invocation that should be concluded once the blob
+	// data has been uploaded.
+	Put ucan.Invocation
+	// Address is where the blob data should be uploaded to. It is nil if the
+	// provider already has the blob.
+	Address *Address
+}
+
+// Acceptance is the result of a storage provider accepting a blob.
+type Acceptance struct {
+	// Commitment is the location commitment issued by the storage provider.
+	Commitment ucan.Invocation
+	// Locations are the URLs the blob can be retrieved from.
+	Locations []*url.URL
+}
+
+// AddBlob invokes /blob/add to add the blob with the passed digest and size to
+// the space.
+func (c *Client) AddBlob(ctx context.Context, space did.DID, digest multihash.Multihash, size uint64) (*Allocation, error) {
+	// create required invocation delegations
+	// TODO: get proof chain for these as well and add to invocation - for now it
+	// is fine as we know we have top authority over the space so this delegation
+	// will be included already.
+	allocDlg, err := delegation.Delegate(
+		c.id,
+		c.services.Upload.ID,
+		space,
+		blob.AllocateCommand,
+		delegation.WithPolicyBuilder(
+			policy.And(
+				policy.Equal(".blob.digest", []byte(digest)),
+				policy.Equal(".blob.size", int64(size)),
+			),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delegating %q: %w", blob.AllocateCommand, err)
+	}
+
+	response, err := invoke(
+		ctx,
+		c,
+		space,
+		blob.Add,
+		&blob.AddArguments{
+			Blob: blob.Blob{
+				Digest: digest,
+				Size:   size,
+			},
+		},
+		execution.WithDelegations(allocDlg),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	addOut, err := result.MapResultR1(
+		response.Out(),
+		func(o ipld.Any) (blob.AddOK, error) {
+			model := blob.AddOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: blob.AddCommand, Failure: x}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	addOK, _ := result.Unwrap(addOut)
+
+	// Find /http/put invocation
+	var httpPutInv ucan.Invocation
+	for _, inv := range response.Metadata().Invocations() {
+		if inv.Command() == http_caps.PutCommand {
+			httpPutInv = inv
+			break
+		}
+	}
+	if httpPutInv == nil {
+		return nil, fmt.Errorf("missing %q invocation in response", http_caps.PutCommand)
+	}
+
+	// Find allocation receipt in the response metadata
+	var allocRcpt ucan.Receipt
+	for _, inv := range response.Metadata().Invocations() {
+		if inv.Command() != blob.AllocateCommand {
+			continue
+		}
+		rcpt, ok := response.Metadata().Receipt(inv.Task().Link())
+		if ok {
+			allocRcpt = rcpt
+			break
+		}
+	}
+	if allocRcpt == nil {
+		return nil, fmt.Errorf("missing %q receipt in response", blob.AllocateCommand)
+	}
+
+	allocOut, err := result.MapResultR1(
+		allocRcpt.Out(),
+		func(o ipld.Any) (blob.AllocateOK, error) {
+			model := blob.AllocateOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: blob.AllocateCommand, Failure: x}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	allocOK, _ := result.Unwrap(allocOut)
+
+	alloc := Allocation{
+		Site:     addOK.Site.Task,
+		Provider: allocRcpt.Issuer().DID(),
+		Put:      httpPutInv,
+	}
+	if allocOK.Address != nil {
+		alloc.Address = &Address{
+			URL:     allocOK.Address.URL.URL(),
+			Headers: allocOK.Address.Headers,
+		}
+	}
+	return &alloc, nil
+}
+
+// PutBlob uploads size bytes of blob data from body to the address allocated
+// by [Client.AddBlob].
+func (c *Client) PutBlob(ctx context.Context, address Address, body io.Reader, size uint64) error {
+	putReq, err := http.NewRequestWithContext(ctx, http.MethodPut, address.URL.String(), io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	putReq.ContentLength = int64(size)
+	for k, v := range address.Headers {
+		putReq.Header.Set(k, v)
+	}
+	putRes, err := c.httpClient.Do(putReq)
+	if err != nil {
+		return fmt.Errorf("uploading blob: %w", err)
+	}
+	defer putRes.Body.Close()
+
+	if putRes.StatusCode < 200 || putRes.StatusCode >= 300 {
+		body, _ := io.ReadAll(putRes.Body)
+		return &PutError{StatusCode: putRes.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// ConcludePut issues a receipt for the /http/put task on behalf of the blob
+// provider, and sends it to the upload service in a /ucan/conclude invocation.
+// It should be called once the blob data has been uploaded. The issued receipt
+// is returned.
+func (c *Client) ConcludePut(ctx context.Context, space did.DID, digest multihash.Multihash, size uint64, put ucan.Invocation) (ucan.Receipt, error) {
+	blobProvider, err := extractBlobProviderKey(put)
+	if err != nil {
+		return nil, fmt.Errorf("extracting blob provider key: %w", err)
+	}
+
+	httpPutRcpt, err := receipt.Issue(
+		blobProvider,
+		put.Task().Link(),
+		result.OK[ipld.Map, ipld.Any](ipld.Map{}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("issuing %q receipt: %w", http_caps.PutCommand, err)
+	}
+
+	concludeInv, err := ucan_caps.Conclude.Invoke(
+		c.id,
+		c.id,
+		&ucan_caps.ConcludeArguments{
+			Receipt: httpPutRcpt.Link(),
+		},
+		invocation.WithAudience(c.services.Upload.ID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating %q invocation: %w", ucan_caps.ConcludeCommand, err)
+	}
+
+	accDlg, err := delegation.Delegate(
+		c.id,
+		c.services.Upload.ID,
+		space,
+		blob.AcceptCommand,
+		delegation.WithPolicyBuilder(
+			policy.And(
+				policy.Equal(".blob.digest", []byte(digest)),
+				policy.Equal(".blob.size", int64(size)),
+			),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delegating %q: %w", blob.AcceptCommand, err)
+	}
+
+	request := execution.NewRequest(
+		ctx,
+		concludeInv,
+		execution.WithDelegations(accDlg),
+		execution.WithReceipts(httpPutRcpt),
+	)
+	_, err = c.upload.Execute(request)
+	if err != nil {
+		return nil, fmt.Errorf("executing %q invocation: %w", ucan_caps.ConcludeCommand, err)
+	}
+	return httpPutRcpt, nil
+}
+
+// AwaitAccept polls for the receipt of the /blob/accept task returned by
+// [Client.AddBlob] and returns the location commitment it refers to.
+func (c *Client) AwaitAccept(ctx context.Context, site ucan.Link) (*Acceptance, error) {
+	accRcpt, accRcptCt, err := c.receipts.Poll(ctx, site)
+	if err != nil {
+		return nil, fmt.Errorf("polling for %q receipt: %w", blob.AcceptCommand, err)
+	}
+
+	accOut, err := result.MapResultR1(
+		accRcpt.Out(),
+		func(o ipld.Any) (blob.AcceptOK, error) {
+			model := blob.AcceptOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: blob.AcceptCommand, Failure: x}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	accOK, _ := result.Unwrap(accOut)
+
+	var locationCommitment ucan.Invocation
+	for _, inv := range accRcptCt.Invocations() {
+		if inv.Link() == accOK.Site {
+			locationCommitment = inv
+		}
+	}
+	if locationCommitment == nil {
+		return nil, fmt.Errorf("missing location commitment: %s", accOK.Site)
+	}
+
+	loc := assert_caps.LocationArguments{}
+	err = datamodel.Rebind(datamodel.NewAny(locationCommitment.Arguments()), &loc)
+	if err != nil {
+		return nil, fmt.Errorf("decoding location commitment: %w", err)
+	}
+
+	acc := Acceptance{Commitment: locationCommitment}
+	for _, location := range loc.Location {
+		acc.Locations = append(acc.Locations, location.URL())
+	}
+	return &acc, nil
+}
+
+// UploadBlob adds the blob with the passed digest and size to the space,
+// uploading the data from body if the provider does not already have it, and
+// waits for it to be accepted.
+func (c *Client) UploadBlob(ctx context.Context, space did.DID, body io.Reader, digest multihash.Multihash, size uint64) (*Acceptance, error) {
+	alloc, err := c.AddBlob(ctx, space, digest, size)
+	if err != nil {
+		return nil, err
+	}
+	if alloc.Address != nil {
+		if err := c.PutBlob(ctx, *alloc.Address, body, size); err != nil {
+			return nil, err
+		}
+		if _, err := c.ConcludePut(ctx, space, digest, size, alloc.Put); err != nil {
+			return nil, err
+		}
+	}
+	return c.AwaitAccept(ctx, alloc.Site)
+}
+
+// extractBlobProviderKey extracts the blob provider's signing key from the
+// /http/put invocation metadata.
+func extractBlobProviderKey(inv ucan.Invocation) (principal.Signer, error) {
+	if _, ok := inv.Metadata()["keys"]; !ok {
+		return nil, fmt.Errorf("missing 'keys' metadata")
+	}
+	keyMap, ok := inv.Metadata()["keys"].(ipld.Map)
+	if !ok {
+		return nil, fmt.Errorf("invalid 'keys' metadata: not an IPLD map")
+	}
+	val, ok := keyMap[inv.Issuer().DID().String()]
+	if !ok {
+		return nil, fmt.Errorf("missing private key for %q in 'keys' metadata", inv.Issuer().DID().String())
+	}
+	keyBytes, ok := val.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key for %q in 'keys' metadata: not a byte slice", inv.Issuer().DID().String())
+	}
+	return ed25519.Decode(keyBytes)
+}
@@ -0,0 +1,112 @@
+// Package client provides a client for uploading data to the Storacha Network.
+//
+// The client exposes each step of the upload protocol so that callers can
+// record progress between steps, as well as [Client.UploadBlob] which performs
+// all the steps required to add a blob to a space.
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/alanshaw/buff/pkg/config/app"
+	rcpt_client "github.com/alanshaw/buff/pkg/receipt"
+	dstore "github.com/alanshaw/buff/pkg/store/delegation"
+	ucanlib "github.com/alanshaw/libracha/ucan"
+	ucan_client "github.com/alanshaw/ucantone/client"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/execution"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/invocation"
+	"github.com/alanshaw/ucantone/validator/bindcap"
+)
+
+type Client struct {
+	id          principal.Signer
+	services    app.ExternalServicesConfig
+	delegations dstore.Store
+	httpClient  *http.Client
+	upload      *ucan_client.HTTPClient
+	receipts    *rcpt_client.Client
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient sets the HTTP client used to communicate with the upload
+// service and to upload blob data.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// New creates a client that acts as the passed identity, using delegations
+// from the passed store to prove its authority to invoke capabilities on
+// spaces.
+func New(id principal.Signer, services app.ExternalServicesConfig, delegations dstore.Store, options ...Option) (*Client, error) {
+	c := Client{
+		id:          id,
+		services:    services,
+		delegations: delegations,
+	}
+	for _, o := range options {
+		o(&c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	upload, err := ucan_client.NewHTTP(services.Upload.URL, ucan_client.WithHTTPClient(c.httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("creating upload service client: %w", err)
+	}
+	c.upload = upload
+	c.receipts = rcpt_client.New(services.Upload.URL.JoinPath("receipt"), rcpt_client.WithHTTPClient(c.httpClient))
+
+	return &c, nil
+}
+
+// ID is the identity the client acts as.
+func (c *Client) ID() principal.Signer {
+	return c.id
+}
+
+// invoke invokes the capability on the space, sending the invocation to the
+// upload service along with the proof chain from the delegation store. No proofs
+// are needed when the subject is the client itself.
+func invoke[A bindcap.Arguments](ctx context.Context, c *Client, space did.DID, capability *bindcap.Capability[A], args A, options ...execution.RequestOption) (execution.Response, error) {
+	var (
+		proofs     []ucan.Delegation
+		proofLinks []ucan.Link
+	)
+	if space != c.id.DID() {
+		var err error
+		proofs, proofLinks, err = ucanlib.ProofChain(ctx, c.delegations, c.id, capability.Command(), space)
+		if err != nil {
+			return nil, fmt.Errorf("building proof chain: %w", err)
+		}
+		if len(proofs) == 0 {
+			return nil, &MissingProofsError{Command: capability.Command(), Space: space}
+		}
+	}
+
+	inv, err := capability.Invoke(
+		c.id,
+		space,
+		args,
+		invocation.WithAudience(c.services.Upload.ID),
+		invocation.WithProofs(proofLinks...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating %q invocation: %w", capability.Command(), err)
+	}
+
+	options = append([]execution.RequestOption{execution.WithProofs(proofs...)}, options...)
+	response, err := c.upload.Execute(execution.NewRequest(ctx, inv, options...))
+	if err != nil {
+		return nil, fmt.Errorf("executing %q invocation: %w", capability.Command(), err)
+	}
+	return response, nil
+}
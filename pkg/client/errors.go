@@ -0,0 +1,40 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// MissingProofsError is returned when the delegation store has no delegations
+// that authorize the client to invoke a command on a space.
+type MissingProofsError struct {
+	Command ucan.Command
+	Space   did.DID
+}
+
+func (e *MissingProofsError) Error() string {
+	return fmt.Sprintf("missing %q delegations for space: %s", e.Command, e.Space)
+}
+
+// TaskFailedError is returned when a task completed with a failure.
+type TaskFailedError struct {
+	Command ucan.Command
+	Failure ipld.Any
+}
+
+func (e *TaskFailedError) Error() string {
+	return fmt.Sprintf("failed %q task: %+v", e.Command, e.Failure)
+}
+
+// PutError is returned when the HTTP PUT of blob data is not successful.
+type PutError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *PutError) Error() string {
+	return fmt.Sprintf("upload failed with status %d: %s", e.StatusCode, e.Body)
+}
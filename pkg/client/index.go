@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+
+	index_caps "github.com/alanshaw/buff/pkg/capabilities/index"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/result"
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// AddIndex invokes /index/add for an uploaded sharded DAG index blob so that
+// the indexing service can answer queries for the blocks in the DAG.
+func (c *Client) AddIndex(ctx context.Context, space did.DID, index ucan.Link) error {
+	response, err := invoke(
+		ctx,
+		c,
+		space,
+		index_caps.Add,
+		&index_caps.AddArguments{Index: index},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = result.MapResultR1(
+		response.Out(),
+		func(o ipld.Any) (index_caps.AddOK, error) {
+			model := index_caps.AddOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: index_caps.AddCommand, Failure: x}
+		},
+	)
+	return err
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+
+	ucan_caps "github.com/alanshaw/buff/pkg/capabilities/ucan"
+	"github.com/alanshaw/ucantone/execution"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/result"
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// Revoke invokes /ucan/revoke to revoke the delegation, on the authority of
+// the client. The proof is the chain from the delegation to one issued by the
+// client, and should be empty if the client issued the delegation. The
+// delegation and proof are sent along with the invocation so the service can
+// verify the client is entitled to revoke.
+func (c *Client) Revoke(ctx context.Context, dlg ucan.Delegation, proof []ucan.Delegation) error {
+	var links []ucan.Link
+	for _, p := range proof {
+		links = append(links, p.Link())
+	}
+
+	response, err := invoke(
+		ctx,
+		c,
+		c.id.DID(),
+		ucan_caps.Revoke,
+		&ucan_caps.RevokeArguments{
+			UCAN:  dlg.Link(),
+			Proof: links,
+		},
+		execution.WithDelegations(append([]ucan.Delegation{dlg}, proof...)...),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = result.MapResultR1(
+		response.Out(),
+		func(o ipld.Any) (ucan_caps.RevokeOK, error) {
+			model := ucan_caps.RevokeOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: ucan_caps.RevokeCommand, Failure: x}
+		},
+	)
+	return err
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+
+	upload_caps "github.com/alanshaw/buff/pkg/capabilities/upload"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/result"
+	"github.com/alanshaw/ucantone/ucan"
+)
+
+// AddUpload invokes /upload/add to register the content root with the shards
+// that contain its blocks.
+func (c *Client) AddUpload(ctx context.Context, space did.DID, root ucan.Link, shards []ucan.Link) error {
+	response, err := invoke(
+		ctx,
+		c,
+		space,
+		upload_caps.Add,
+		&upload_caps.AddArguments{
+			Root:   root,
+			Shards: shards,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = result.MapResultR1(
+		response.Out(),
+		func(o ipld.Any) (upload_caps.AddOK, error) {
+			model := upload_caps.AddOK{}
+			err = datamodel.Rebind(datamodel.NewAny(o), &model)
+			return model, err
+		},
+		func(x ipld.Any) (error, error) {
+			return nil, &TaskFailedError{Command: upload_caps.AddCommand, Failure: x}
+		},
+	)
+	return err
+}
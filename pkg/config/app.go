@@ -10,6 +10,7 @@ type AppConfig struct {
 	Identity IdentityConfig `mapstructure:"identity" toml:"identity"`
 	Repo     RepoConfig     `mapstructure:"repo" toml:"repo"`
 	Services ServicesConfig `mapstructure:"services" toml:"services"`
+	Upload   UploadConfig   `mapstructure:"upload" toml:"upload"`
 }
 
 func (f AppConfig) Validate() error {
@@ -17,7 +18,11 @@ func (f AppConfig) Validate() error {
 }
 
 // Normalize applies compatibility fixes before validation.
-func (f *AppConfig) Normalize() {}
+func (f *AppConfig) Normalize() {
+	if f.Upload.Concurrency == 0 {
+		f.Upload.Concurrency = DefaultUploadConcurrency
+	}
+}
 
 func (f AppConfig) ToAppConfig() (app.AppConfig, error) {
 	var (
@@ -40,5 +45,10 @@ func (f AppConfig) ToAppConfig() (app.AppConfig, error) {
 		return app.AppConfig{}, fmt.Errorf("converting services to app config: %w", err)
 	}
 
+	out.Upload, err = f.Upload.ToAppConfig()
+	if err != nil {
+		return app.AppConfig{}, fmt.Errorf("converting upload to app config: %w", err)
+	}
+
 	return out, nil
 }
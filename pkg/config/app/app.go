@@ -5,4 +5,5 @@ type AppConfig struct {
 	Identity IdentityConfig
 	Storage  StorageConfig
 	Services ExternalServicesConfig
+	Upload   UploadConfig
 }
@@ -4,8 +4,34 @@ type StorageConfig struct {
 	DataDir string
 	// Service-specific storage configurations
 	Delegation DelegationStorageConfig
+	Upload     UploadStorageConfig
 }
 
+// Delegation store backends.
+const (
+	LevelDBBackend = "leveldb"
+	MemoryBackend  = "memory"
+	FilesBackend   = "files"
+	BoltBackend    = "bolt"
+)
+
 type DelegationStorageConfig struct {
+	// Backend is the datastore delegations are kept in.
+	Backend string
+	// Dir is the directory the backend stores its data in. It is not used by
+	// the memory backend.
+	Dir string
+	// Passphrase, if set, returns the passphrase used to derive the key
+	// delegations are encrypted with. It is only called when the store is
+	// opened, so that commands that do not use the store do not ask for it.
+	Passphrase func() ([]byte, error)
+	// GCOnStart deletes expired delegations when the store is opened.
+	GCOnStart bool
+}
+
+type UploadStorageConfig struct {
 	Dir string
+	// StagingDir is where data for in-progress uploads is kept until the upload
+	// completes.
+	StagingDir string
 }
@@ -0,0 +1,7 @@
+package app
+
+// UploadConfig contains configuration for uploading data
+type UploadConfig struct {
+	// Concurrency is the maximum number of blobs uploaded in parallel.
+	Concurrency int
+}
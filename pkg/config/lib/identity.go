@@ -4,14 +4,19 @@ import (
 	crypto_ed25519 "crypto/ed25519"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/alanshaw/ucantone/principal"
 	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/youmark/pkcs8"
 )
 
+// SignerFromEd25519PEMFile reads an ed25519 private key from a PKCS#8 PEM file.
+// If the key is encrypted ("ENCRYPTED PRIVATE KEY"), it is decrypted with the
+// passphrase returned by [Passphrase].
 func SignerFromEd25519PEMFile(path string) (principal.Signer, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -22,7 +27,12 @@ func SignerFromEd25519PEMFile(path string) (principal.Signer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading private key: %w", err)
 	}
+	return SignerFromEd25519PEM(pemData)
+}
 
+// SignerFromEd25519PEM decodes an ed25519 private key from PKCS#8 PEM data, as
+// [SignerFromEd25519PEMFile] does.
+func SignerFromEd25519PEM(pemData []byte) (principal.Signer, error) {
 	var privateKey *crypto_ed25519.PrivateKey
 	rest := pemData
 
@@ -35,11 +45,24 @@ func SignerFromEd25519PEMFile(path string) (principal.Signer, error) {
 		}
 		rest = remaining
 
-		// Look for "PRIVATE KEY"
-		if block.Type == "PRIVATE KEY" {
-			parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+		// Look for "PRIVATE KEY" or "ENCRYPTED PRIVATE KEY"
+		if block.Type == "PRIVATE KEY" || block.Type == "ENCRYPTED PRIVATE KEY" {
+			var parsedKey any
+			if block.Type == "ENCRYPTED PRIVATE KEY" {
+				passphrase, err := Passphrase()
+				if err != nil {
+					return nil, err
+				}
+				parsedKey, _, err = pkcs8.ParsePrivateKey(block.Bytes, passphrase)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decrypt PKCS#8 private key (incorrect passphrase?): %w", err)
+				}
+			} else {
+				var err error
+				parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+				}
 			}
 
 			// We expect a ed25519 private key, cast it
@@ -53,7 +76,61 @@ func SignerFromEd25519PEMFile(path string) (principal.Signer, error) {
 	}
 
 	if privateKey == nil {
-		return nil, fmt.Errorf("could not find a PRIVATE KEY block in the PEM file")
+		return nil, fmt.Errorf("could not find a PRIVATE KEY or ENCRYPTED PRIVATE KEY block in the PEM file")
 	}
 	return ed25519.FromRaw(privateKey.Seed())
 }
+
+// EncodeEd25519PEM encodes an ed25519 signer as PKCS#8 PEM data. If passphrase
+// is not nil, the key is encrypted with it.
+func EncodeEd25519PEM(signer principal.Signer, passphrase []byte) ([]byte, error) {
+	if signer.Code() != ed25519.Code {
+		return nil, fmt.Errorf("not an ed25519 signer: 0x%x", signer.Code())
+	}
+	key := crypto_ed25519.NewKeyFromSeed(signer.Raw())
+
+	block := &pem.Block{Type: "PRIVATE KEY"}
+	var err error
+	if passphrase == nil {
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(key)
+	} else {
+		block.Type = "ENCRYPTED PRIVATE KEY"
+		block.Bytes, err = pkcs8.MarshalPrivateKey(key, passphrase, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("marshaling PKCS#8 private key: %w", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// WriteEd25519PEMFile writes an ed25519 signer to a PKCS#8 PEM file that only
+// the current user can read. If passphrase is not nil, the key is encrypted
+// with it. An existing file is only overwritten if overwrite is true.
+func WriteEd25519PEMFile(path string, signer principal.Signer, passphrase []byte, overwrite bool) error {
+	pemData, err := EncodeEd25519PEM(signer, passphrase)
+	if err != nil {
+		return err
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("key file already exists: %s", path)
+		}
+		return err
+	}
+	// an overwritten file keeps its permissions
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return fmt.Errorf("setting key file permissions: %w", err)
+	}
+	if _, err := f.Write(pemData); err != nil {
+		f.Close()
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,73 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnvVar is the environment variable the passphrase is read from. If
+// it is not set, the passphrase is read from the terminal.
+const PassphraseEnvVar = "BUFF_PASSPHRASE"
+
+var readPassphrase = sync.OnceValues(func() ([]byte, error) {
+	if p, ok := os.LookupEnv(PassphraseEnvVar); ok {
+		return []byte(p), nil
+	}
+	return promptPassphrase("🔑 Enter passphrase: ")
+})
+
+// Passphrase returns the passphrase from the BUFF_PASSPHRASE environment
+// variable, or prompts for it on the terminal. The passphrase is read at most
+// once, subsequent calls return the same value.
+func Passphrase() ([]byte, error) {
+	return readPassphrase()
+}
+
+// NewPassphrase returns the passphrase from the BUFF_PASSPHRASE environment
+// variable, or prompts for a new passphrase on the terminal, twice, to confirm
+// it has been entered correctly.
+func NewPassphrase() ([]byte, error) {
+	if p, ok := os.LookupEnv(PassphraseEnvVar); ok {
+		return []byte(p), nil
+	}
+	p, err := promptPassphrase("🔑 Enter new passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	confirm, err := promptPassphrase("🔑 Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if string(p) != string(confirm) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+func promptPassphrase(prompt string) ([]byte, error) {
+	// prefer the controlling terminal, so that the passphrase can be entered
+	// when stdin is piped
+	tty, err := os.Open("/dev/tty")
+	if err == nil {
+		defer tty.Close()
+	} else if term.IsTerminal(int(os.Stdin.Fd())) {
+		tty = os.Stdin
+	} else {
+		return nil, fmt.Errorf("no terminal to read passphrase from, set %s", PassphraseEnvVar)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return p, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is the name of the profile made up of the top level settings
+// in the config file.
+const DefaultProfile = "default"
+
+// ApplyProfile merges the settings of the named profile, from the "profiles"
+// table in the config file, over the top level settings. Flags and environment
+// variables still take precedence over profile settings. Profile names are
+// case-insensitive, since keys in the config file are.
+func ApplyProfile(name string) error {
+	name = strings.ToLower(name)
+	if name == "" || name == DefaultProfile {
+		return nil
+	}
+	if !slices.Contains(Profiles(), name) {
+		return fmt.Errorf("unknown profile: %q (available profiles are: %q)", name, Profiles())
+	}
+	return viper.MergeConfigMap(viper.GetStringMap("profiles." + name))
+}
+
+// Profiles returns the names of the profiles in the config file, including the
+// default profile.
+func Profiles() []string {
+	names := lo.Keys(viper.GetStringMap("profiles"))
+	slices.Sort(names)
+	return append([]string{DefaultProfile}, names...)
+}
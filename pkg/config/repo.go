@@ -1,14 +1,37 @@
 package config
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/config/lib"
 )
 
 type RepoConfig struct {
-	DataDir string `mapstructure:"data_dir" validate:"required" flag:"data-dir" toml:"data_dir"`
+	DataDir    string               `mapstructure:"data_dir" validate:"required" flag:"data-dir" toml:"data_dir"`
+	Delegation DelegationRepoConfig `mapstructure:"delegation" toml:"delegation,omitempty"`
+}
+
+type DelegationRepoConfig struct {
+	// Backend is the datastore delegations are kept in, one of "leveldb" (the
+	// default), "memory", "files" (a plain file per key) or "bolt" (a single
+	// database file). The files backend keeps its files in a single directory,
+	// which may be a read-only mount, such as a Kubernetes Secret volume, of a
+	// directory created by buff. Its writes are not atomic, run "repo fsck
+	// --repair" if buff is interrupted while writing to it. With the memory
+	// backend, the data dir is only written to by uploads, which keep the
+	// upload journal and staged files in it.
+	Backend string `mapstructure:"backend" validate:"omitempty,oneof=leveldb memory files bolt" toml:"backend,omitempty"`
+	// Dir is the directory the backend stores its data in. It defaults to a
+	// directory in the data dir.
+	Dir string `mapstructure:"dir" toml:"dir,omitempty"`
+	// Encrypt encrypts delegations in the store with a key derived from the
+	// passphrase, which is read from BUFF_PASSPHRASE or the terminal. Existing
+	// delegations are encrypted when it is first enabled. It cannot be disabled
+	// once delegations have been encrypted.
+	Encrypt bool `mapstructure:"encrypt" toml:"encrypt,omitempty"`
+	// GCOnStart deletes expired delegations from the store each time buff runs.
+	GCOnStart bool `mapstructure:"gc_on_start" toml:"gc_on_start,omitempty"`
 }
 
 func (r RepoConfig) Validate() error {
@@ -20,14 +43,36 @@ func (r RepoConfig) ToAppConfig() (app.StorageConfig, error) {
 		return app.StorageConfig{}, nil
 	}
 
-	if err := os.MkdirAll(r.DataDir, 0755); err != nil {
-		return app.StorageConfig{}, err
+	backend := r.Delegation.Backend
+	if backend == "" {
+		backend = app.LevelDBBackend
+	}
+	dlgDir := r.Delegation.Dir
+	if dlgDir == "" {
+		dlgDir = filepath.Join(r.DataDir, "delegation", "datastore")
+		// keep the data of each backend apart, so that switching backends does
+		// not mix incompatible files
+		if backend != app.LevelDBBackend {
+			dlgDir = filepath.Join(r.DataDir, "delegation", backend)
+		}
+	}
+
+	var passphrase func() ([]byte, error)
+	if r.Delegation.Encrypt {
+		passphrase = lib.Passphrase
 	}
 
 	out := app.StorageConfig{
 		DataDir: r.DataDir,
 		Delegation: app.DelegationStorageConfig{
-			Dir: filepath.Join(r.DataDir, "delegation", "datastore"),
+			Backend:    backend,
+			Dir:        dlgDir,
+			Passphrase: passphrase,
+			GCOnStart:  r.Delegation.GCOnStart,
+		},
+		Upload: app.UploadStorageConfig{
+			Dir:        filepath.Join(r.DataDir, "upload", "datastore"),
+			StagingDir: filepath.Join(r.DataDir, "upload", "staging"),
 		},
 	}
 
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"github.com/alanshaw/buff/pkg/config/app"
+)
+
+// DefaultUploadConcurrency is the default maximum number of blobs uploaded in
+// parallel.
+const DefaultUploadConcurrency = 4
+
+type UploadConfig struct {
+	Concurrency int `mapstructure:"concurrency" validate:"min=1" flag:"concurrency" toml:"concurrency,omitempty"`
+}
+
+func (u UploadConfig) Validate() error {
+	return validateConfig(u)
+}
+
+func (u UploadConfig) ToAppConfig() (app.UploadConfig, error) {
+	return app.UploadConfig{
+		Concurrency: u.Concurrency,
+	}, nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/fx/client"
 	"github.com/alanshaw/buff/pkg/fx/identity"
 	"github.com/alanshaw/buff/pkg/fx/store"
 	"go.uber.org/fx"
@@ -16,8 +17,10 @@ func CommonModules(cfg app.AppConfig) fx.Option {
 		fx.Supply(cfg.Identity),
 		fx.Supply(cfg.Storage),
 		fx.Supply(cfg.Services),
+		fx.Supply(cfg.Upload),
 
 		identity.Module,
 		store.Module,
+		client.Module,
 	)
 }
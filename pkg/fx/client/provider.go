@@ -0,0 +1,17 @@
+package client
+
+import (
+	"github.com/alanshaw/buff/pkg/client"
+	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/alanshaw/ucantone/principal"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module("client",
+	fx.Provide(NewClient),
+)
+
+func NewClient(id principal.Signer, cfg app.ExternalServicesConfig, delegationStore delegation.Store) (*client.Client, error) {
+	return client.New(id, cfg, delegationStore)
+}
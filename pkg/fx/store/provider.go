@@ -6,40 +6,100 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/alanshaw/buff/pkg/store/datastore/bolt"
+	"github.com/alanshaw/buff/pkg/store/datastore/crypt"
+	"github.com/alanshaw/buff/pkg/store/datastore/files"
 	"github.com/alanshaw/buff/pkg/store/delegation"
+	"github.com/alanshaw/buff/pkg/store/upload"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	leveldb "github.com/ipfs/go-ds-leveldb"
+	logging "github.com/ipfs/go-log/v2"
 	"go.uber.org/fx"
 
 	"github.com/alanshaw/buff/pkg/config/app"
 )
 
+var log = logging.Logger("pkg/fx/store")
+
 var Module = fx.Module("store",
 	fx.Provide(
 		ProvideConfigs,
 		NewDelegationStore,
+		NewUploadStore,
 	),
 )
 
 type Configs struct {
 	fx.Out
 	Delegation app.DelegationStorageConfig
+	Upload     app.UploadStorageConfig
 }
 
 // ProvideConfigs provides the fields of a storage config
 func ProvideConfigs(cfg app.StorageConfig) Configs {
 	return Configs{
 		Delegation: cfg.Delegation,
+		Upload:     cfg.Upload,
 	}
 }
 
 func NewDelegationStore(cfg app.DelegationStorageConfig, lc fx.Lifecycle) (delegation.Store, error) {
+	ds, err := newDelegationDatastore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating delegation store: %w", err)
+	}
+	if cfg.Passphrase != nil {
+		passphrase, err := cfg.Passphrase()
+		if err != nil {
+			ds.Close()
+			return nil, fmt.Errorf("reading delegation store passphrase: %w", err)
+		}
+		cds, err := crypt.NewDatastore(context.Background(), ds, passphrase)
+		if err != nil {
+			ds.Close()
+			return nil, fmt.Errorf("opening encrypted delegation store: %w", err)
+		}
+		ds = cds
+	} else if encrypted, err := crypt.IsEncrypted(context.Background(), ds); err != nil || encrypted {
+		ds.Close()
+		if err != nil {
+			return nil, fmt.Errorf("checking delegation store encryption: %w", err)
+		}
+		return nil, fmt.Errorf("delegation store is encrypted, set repo.delegation.encrypt to open it")
+	}
+
+	store := delegation.NewDSDelegationStore(ds)
+	if err := store.Migrate(context.Background()); err != nil {
+		ds.Close()
+		return nil, fmt.Errorf("migrating delegation store: %w", err)
+	}
+
+	// commands run from fx.Invoke, before OnStart hooks, so expired delegations
+	// are collected here for the command to see the collected store
+	if cfg.GCOnStart {
+		if _, err := store.GC(context.Background()); err != nil {
+			log.Warnw("collecting expired delegations", "error", err)
+		}
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return ds.Close()
+		},
+	})
+
+	return store, nil
+}
+
+func NewUploadStore(cfg app.UploadStorageConfig, lc fx.Lifecycle) (upload.Store, error) {
 	if cfg.Dir == "" {
-		return nil, fmt.Errorf("no data dir provided for provider store")
+		return nil, fmt.Errorf("no data dir provided for upload store")
 	}
 
 	ds, err := newDatastore(cfg.Dir)
 	if err != nil {
-		return nil, fmt.Errorf("creating provider store: %w", err)
+		return nil, fmt.Errorf("creating upload store: %w", err)
 	}
 
 	lc.Append(fx.Hook{
@@ -48,7 +108,37 @@ func NewDelegationStore(cfg app.DelegationStorageConfig, lc fx.Lifecycle) (deleg
 		},
 	})
 
-	return delegation.NewDSDelegationStore(ds), nil
+	return upload.NewDSUploadStore(ds), nil
+}
+
+// newDelegationDatastore creates the datastore for the configured delegation
+// store backend.
+func newDelegationDatastore(cfg app.DelegationStorageConfig) (datastore.Batching, error) {
+	if cfg.Backend == app.MemoryBackend {
+		return dssync.MutexWrap(datastore.NewMapDatastore()), nil
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("no data dir provided for delegation store")
+	}
+
+	switch cfg.Backend {
+	case "", app.LevelDBBackend:
+		return newDatastore(cfg.Dir)
+	case app.FilesBackend:
+		dirPath, err := mkdirp(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("creating files datastore at path %s: %w", cfg.Dir, err)
+		}
+		return files.NewDatastore(dirPath)
+	case app.BoltBackend:
+		dirPath, err := mkdirp(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("creating bolt datastore at path %s: %w", cfg.Dir, err)
+		}
+		return bolt.NewDatastore(filepath.Join(dirPath, "delegations.db"))
+	default:
+		return nil, fmt.Errorf("unknown delegation store backend: %s", cfg.Backend)
+	}
 }
 
 func newDatastore(path string) (*leveldb.Datastore, error) {
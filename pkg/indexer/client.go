@@ -0,0 +1,107 @@
+// Package indexer provides a client for querying the indexing service for
+// claims about content.
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/transport"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/multiformats/go-multihash"
+)
+
+type ResponseDecoder[Res any] interface {
+	Decode(Res) (ucan.Container, error)
+}
+
+type Client struct {
+	endpoint *url.URL
+	client   *http.Client
+	codec    ResponseDecoder[*http.Response]
+}
+
+type Option func(c *Client)
+
+func WithCodec(codec ResponseDecoder[*http.Response]) Option {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+func New(endpoint *url.URL, options ...Option) *Client {
+	c := Client{
+		endpoint: endpoint,
+		codec:    transport.DefaultHTTPOutboundCodec,
+	}
+	for _, o := range options {
+		o(&c)
+	}
+	if c.client == nil {
+		c.client = http.DefaultClient
+	}
+	return &c
+}
+
+type queryConfig struct {
+	spaces []did.DID
+}
+
+type QueryOption func(cfg *queryConfig)
+
+// WithSpaces restricts the query to claims made about content in the passed
+// spaces.
+func WithSpaces(spaces ...did.DID) QueryOption {
+	return func(cfg *queryConfig) {
+		cfg.spaces = append(cfg.spaces, spaces...)
+	}
+}
+
+// Query the indexing service for claims about the content identified by the
+// passed digest. The returned container holds the claims (invocations) and
+// any related delegations and receipts.
+func (c *Client) Query(ctx context.Context, digest multihash.Multihash, options ...QueryOption) (ucan.Container, error) {
+	cfg := queryConfig{}
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	queryURL := c.endpoint.JoinPath("claims")
+	params := url.Values{}
+	params.Set("multihash", digestutil.Format(digest))
+	for _, s := range cfg.spaces {
+		params.Add("spaces", s.String())
+	}
+	queryURL.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating query request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing query request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	ct, err := c.codec.Decode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("decoding query result: %w", err)
+	}
+	return ct, nil
+}
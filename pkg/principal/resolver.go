@@ -0,0 +1,139 @@
+// Package principal provides utilities for resolving verifiers for DIDs.
+package principal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/principal"
+	edverifier "github.com/alanshaw/ucantone/principal/ed25519/verifier"
+	"github.com/alanshaw/ucantone/principal/verifier"
+)
+
+const webPrefix = did.Prefix + "web:"
+
+// didDocument is the subset of a DID document needed to resolve a key.
+type didDocument struct {
+	ID                 string `json:"id"`
+	VerificationMethod []struct {
+		ID                 string `json:"id"`
+		Controller         string `json:"controller"`
+		PublicKeyMultibase string `json:"publicKeyMultibase"`
+	} `json:"verificationMethod"`
+}
+
+// Resolver resolves verifiers for DIDs, caching the verifier for each DID for
+// the life of the resolver.
+type Resolver struct {
+	client *http.Client
+	mutex  sync.Mutex
+	cache  map[string]principal.Verifier
+}
+
+// NewResolver creates a resolver that fetches DID documents with the passed
+// HTTP client, or [http.DefaultClient] if it is nil.
+func NewResolver(client *http.Client) *Resolver {
+	return &Resolver{client: client, cache: map[string]principal.Verifier{}}
+}
+
+// ResolveVerifier returns a verifier for the passed DID, as [ResolveVerifier]
+// does, resolving each DID at most once.
+func (r *Resolver) ResolveVerifier(ctx context.Context, id did.DID) (principal.Verifier, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if v, ok := r.cache[id.String()]; ok {
+		return v, nil
+	}
+	v, err := ResolveVerifier(ctx, r.client, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[id.String()] = v
+	return v, nil
+}
+
+// ResolveVerifier returns a verifier for the passed DID. did:key DIDs are
+// parsed directly. did:web DIDs are resolved by fetching the DID document from
+// "https://<host>/.well-known/did.json", or "https://<host>/<path>/did.json"
+// for a DID with a path e.g. did:web:example.com:user:alice, and using the
+// first ed25519 verification method.
+func ResolveVerifier(ctx context.Context, client *http.Client, id did.DID) (principal.Verifier, error) {
+	str := id.String()
+	if strings.HasPrefix(str, did.KeyPrefix) {
+		return edverifier.Parse(str)
+	}
+	if !strings.HasPrefix(str, webPrefix) {
+		return nil, fmt.Errorf("unsupported DID method: %s", str)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	docURL, err := webDocumentURL(str)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating DID document request: %w", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching DID document: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching DID document: unexpected status: %s", res.Status)
+	}
+
+	var doc didDocument
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding DID document: %w", err)
+	}
+	if doc.ID != str {
+		return nil, fmt.Errorf("DID document id %q does not match %q", doc.ID, str)
+	}
+	for _, vm := range doc.VerificationMethod {
+		if vm.PublicKeyMultibase == "" {
+			continue
+		}
+		key, err := edverifier.Parse(did.KeyPrefix + vm.PublicKeyMultibase)
+		if err != nil {
+			continue
+		}
+		return verifier.Wrap(key, id)
+	}
+	return nil, fmt.Errorf("no ed25519 verification method found for %s", str)
+}
+
+// webDocumentURL returns the URL of the DID document for a did:web DID.
+func webDocumentURL(id string) (string, error) {
+	segments := strings.Split(strings.TrimPrefix(id, webPrefix), ":")
+	for i, seg := range segments {
+		s, err := url.PathUnescape(seg)
+		if err != nil {
+			return "", fmt.Errorf("invalid did:web %q: %w", id, err)
+		}
+		segments[i] = s
+	}
+	host, path := segments[0], segments[1:]
+	if host == "" || strings.Contains(host, "/") {
+		return "", fmt.Errorf("invalid did:web host: %q", host)
+	}
+	for _, seg := range path {
+		if seg == "" || seg == "." || seg == ".." || strings.Contains(seg, "/") {
+			return "", fmt.Errorf("invalid did:web path segment: %q", seg)
+		}
+	}
+	if len(path) == 0 {
+		return fmt.Sprintf("https://%s/.well-known/did.json", host), nil
+	}
+	u := url.URL{Scheme: "https", Host: host}
+	return u.JoinPath(append(path, "did.json")...).String(), nil
+}
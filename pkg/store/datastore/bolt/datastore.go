@@ -0,0 +1,179 @@
+// Package bolt is a datastore backed by a single bbolt database file.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("datastore")
+
+// Datastore stores keys and values in a bucket of a bbolt database.
+type Datastore struct {
+	db *bbolt.DB
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+// NewDatastore opens, or creates, the bbolt database file at path. The file is
+// locked while open, so opening fails after a second if another process has it
+// open.
+func NewDatastore(path string) (*Datastore, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		if errors.Is(err, bbolt.ErrTimeout) {
+			return nil, fmt.Errorf("opening bolt database %s: the data dir is in use by another process: %w", path, err)
+		}
+		return nil, fmt.Errorf("opening bolt database: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating bucket: %w", err)
+	}
+	return &Datastore{db: db}, nil
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	var value []byte
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key.Bytes())
+		if v == nil {
+			return ds.ErrNotFound
+		}
+		// values are only valid for the life of the transaction
+		value = bytes.Clone(v)
+		return nil
+	})
+	return value, err
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	var exists bool
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		exists = tx.Bucket(bucket).Get(key.Bytes()) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	size := -1
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key.Bytes())
+		if v == nil {
+			return ds.ErrNotFound
+		}
+		size = len(v)
+		return nil
+	})
+	return size, err
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put(key.Bytes(), value)
+	})
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key.Bytes())
+	})
+}
+
+func (d *Datastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	prefix := ds.NewKey(q.Prefix).String()
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	var entries []query.Entry
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			entry := query.Entry{Key: string(k), Size: len(v)}
+			if !q.KeysOnly {
+				entry.Value = bytes.Clone(v)
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the prefix has been applied, filters, orders, offset and limit have not
+	naive := q
+	naive.Prefix = ""
+	return query.NaiveQueryApply(naive, query.ResultsWithEntries(q, entries)), nil
+}
+
+// Sync is a no-op, bbolt transactions are synced to disk when committed.
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	return nil
+}
+
+func (d *Datastore) Close() error {
+	return d.db.Close()
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return &batch{db: d.db}, nil
+}
+
+type op struct {
+	key    ds.Key
+	value  []byte
+	delete bool
+}
+
+// batch applies its operations in a single transaction when committed.
+type batch struct {
+	db  *bbolt.DB
+	ops []op
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	b.ops = append(b.ops, op{key: key, value: value})
+	return nil
+}
+
+func (b *batch) Delete(ctx context.Context, key ds.Key) error {
+	b.ops = append(b.ops, op{key: key, delete: true})
+	return nil
+}
+
+func (b *batch) Commit(ctx context.Context) error {
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+		for _, o := range b.ops {
+			var err error
+			if o.delete {
+				err = bkt.Delete(o.key.Bytes())
+			} else {
+				err = bkt.Put(o.key.Bytes(), o.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,36 @@
+package bolt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/testutil"
+)
+
+func newTestDatastore(t *testing.T, path string) *Datastore {
+	t.Helper()
+	d, err := NewDatastore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestSuite(t *testing.T) {
+	testutil.SubtestDatastore(t, newTestDatastore(t, filepath.Join(t.TempDir(), "test.db")))
+}
+
+func TestInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	newTestDatastore(t, path)
+
+	_, err := NewDatastore(path)
+	if err == nil {
+		t.Fatal("expected an error opening a database that is in use")
+	}
+	if !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("expected an in use error, got: %s", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Package crypt is a datastore that encrypts values before they are written to
+// an underlying datastore. Keys are not encrypted, so anything a key is made of
+// remains readable. For the delegation store that includes the DIDs of the
+// issuer, audience and subject, and the command, of every delegation.
+//
+// Values are encrypted with XChaCha20-Poly1305, using a key derived from a
+// passphrase with scrypt. The salt, and a value used to check the passphrase,
+// are stored in the underlying datastore under the "/meta/crypt" prefix.
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	metaPrefix = ds.NewKey("meta/crypt")
+	saltKey    = metaPrefix.ChildString("salt")
+	checkKey   = metaPrefix.ChildString("check")
+)
+
+// checkValue is encrypted and stored under checkKey, so that an incorrect
+// passphrase can be detected when the datastore is opened.
+var checkValue = []byte("buff")
+
+// ErrIncorrectPassphrase is returned when the passphrase does not match the one
+// the datastore was created with.
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// Datastore encrypts values written to, and decrypts values read from, the
+// underlying datastore.
+type Datastore struct {
+	child ds.Batching
+	aead  cipher.AEAD
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+// NewDatastore creates a datastore that encrypts values with a key derived from
+// the passphrase. If the underlying datastore has not been encrypted before, a
+// new salt is generated and any existing values are encrypted.
+func NewDatastore(ctx context.Context, child ds.Batching, passphrase []byte) (*Datastore, error) {
+	salt, err := child.Get(ctx, saltKey)
+	if err != nil && !errors.Is(err, ds.ErrNotFound) {
+		return nil, fmt.Errorf("getting salt: %w", err)
+	}
+	initialized := err == nil
+	if !initialized {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("generating salt: %w", err)
+		}
+	}
+
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	d := &Datastore{child: child, aead: aead}
+
+	if initialized {
+		b, err := child.Get(ctx, checkKey)
+		if err != nil {
+			return nil, fmt.Errorf("getting passphrase check: %w", err)
+		}
+		v, err := d.open(checkKey, b)
+		if err != nil || !bytes.Equal(v, checkValue) {
+			return nil, ErrIncorrectPassphrase
+		}
+		return d, nil
+	}
+
+	if err := d.encryptAll(ctx, salt); err != nil {
+		return nil, fmt.Errorf("encrypting existing values: %w", err)
+	}
+	return d, nil
+}
+
+// IsEncrypted returns true if the values in the datastore have been encrypted.
+func IsEncrypted(ctx context.Context, d ds.Datastore) (bool, error) {
+	return d.Has(ctx, saltKey)
+}
+
+// encryptAll encrypts the existing values of the underlying datastore and
+// stores the salt and passphrase check, in a single batch.
+func (d *Datastore) encryptAll(ctx context.Context, salt []byte) error {
+	results, err := d.child.Query(ctx, query.Query{})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	batch, err := d.child.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return entry.Error
+		}
+		key := ds.NewKey(entry.Key)
+		if err := batch.Put(ctx, key, d.seal(key, entry.Value)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(ctx, saltKey, salt); err != nil {
+		return err
+	}
+	if err := batch.Put(ctx, checkKey, d.seal(checkKey, checkValue)); err != nil {
+		return err
+	}
+	return batch.Commit(ctx)
+}
+
+// seal encrypts the value. The key is authenticated so that values cannot be
+// moved between keys.
+func (d *Datastore) seal(key ds.Key, value []byte) []byte {
+	nonce := make([]byte, d.aead.NonceSize(), d.aead.NonceSize()+len(value)+d.aead.Overhead())
+	// rand.Read never returns an error
+	rand.Read(nonce)
+	return d.aead.Seal(nonce, nonce, value, key.Bytes())
+}
+
+func (d *Datastore) open(key ds.Key, value []byte) ([]byte, error) {
+	if len(value) < d.aead.NonceSize() {
+		return nil, fmt.Errorf("decrypting value for %s: ciphertext too short", key)
+	}
+	nonce, ciphertext := value[:d.aead.NonceSize()], value[d.aead.NonceSize():]
+	plaintext, err := d.aead.Open(nil, nonce, ciphertext, key.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("decrypting value for %s: %w", key, err)
+	}
+	return plaintext, nil
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	b, err := d.child.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return d.open(key, b)
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	return d.child.Has(ctx, key)
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	size, err := d.child.GetSize(ctx, key)
+	if err != nil {
+		return size, err
+	}
+	return size - d.aead.NonceSize() - d.aead.Overhead(), nil
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return d.child.Put(ctx, key, d.seal(key, value))
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	return d.child.Delete(ctx, key)
+}
+
+// Query decrypts the values found by the underlying datastore. Filters and
+// orders are applied to the decrypted entries.
+func (d *Datastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	results, err := d.child.Query(ctx, query.Query{Prefix: q.Prefix})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var entries []query.Entry
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		if strings.HasPrefix(entry.Key, metaPrefix.String()+"/") {
+			continue
+		}
+		key := ds.NewKey(entry.Key)
+		value, err := d.open(key, entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry.Size = len(value)
+		if !q.KeysOnly {
+			entry.Value = value
+		} else {
+			entry.Value = nil
+		}
+		entries = append(entries, entry.Entry)
+	}
+
+	naive := q
+	naive.Prefix = ""
+	return query.NaiveQueryApply(naive, query.ResultsWithEntries(q, entries)), nil
+}
+
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	return d.child.Sync(ctx, prefix)
+}
+
+func (d *Datastore) Close() error {
+	return d.child.Close()
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	b, err := d.child.Batch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &batch{b, d}, nil
+}
+
+// batch encrypts values put in the batch.
+type batch struct {
+	ds.Batch
+	d *Datastore
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return b.Batch.Put(ctx, key, b.d.seal(key, value))
+}
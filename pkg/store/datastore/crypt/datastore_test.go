@@ -0,0 +1,144 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/testutil"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+func newChild() ds.Batching {
+	return dssync.MutexWrap(ds.NewMapDatastore())
+}
+
+func newTestDatastore(t *testing.T, child ds.Batching, passphrase string) *Datastore {
+	t.Helper()
+	d, err := NewDatastore(context.Background(), child, []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSuite(t *testing.T) {
+	testutil.SubtestDatastore(t, newTestDatastore(t, newChild(), "secret"))
+}
+
+func TestPassphrase(t *testing.T) {
+	ctx := context.Background()
+	child := newChild()
+	d := newTestDatastore(t, child, "secret")
+	key := ds.NewKey("foo")
+	if err := d.Put(ctx, key, []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		passphrase string
+		err        error
+	}{
+		{"correct", "secret", nil},
+		{"incorrect", "wrong", ErrIncorrectPassphrase},
+		{"empty", "", ErrIncorrectPassphrase},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewDatastore(ctx, child, []byte(tc.passphrase))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("opening with %q returned %v, expected %v", tc.passphrase, err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			v, err := d.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(v) != "bar" {
+				t.Fatalf("got %q, expected %q", v, "bar")
+			}
+		})
+	}
+}
+
+func TestEncryptsAtRest(t *testing.T) {
+	ctx := context.Background()
+	child := newChild()
+	existing := ds.NewKey("existing")
+	if err := child.Put(ctx, existing, []byte("plaintext existing")); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted, err := IsEncrypted(ctx, child); err != nil || encrypted {
+		t.Fatalf("new datastore is encrypted: %t, %v", encrypted, err)
+	}
+
+	d := newTestDatastore(t, child, "secret")
+	if encrypted, err := IsEncrypted(ctx, child); err != nil || !encrypted {
+		t.Fatalf("datastore is not encrypted after opening: %t, %v", encrypted, err)
+	}
+	added := ds.NewKey("added")
+	if err := d.Put(ctx, added, []byte("plaintext added")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []ds.Key{existing, added} {
+		raw, err := child.Get(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(raw, []byte("plaintext")) {
+			t.Fatalf("value for %s is stored in plaintext", k)
+		}
+		v, err := d.Get(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(v, []byte("plaintext")) {
+			t.Fatalf("value for %s decrypted to %q", k, v)
+		}
+		size, err := d.GetSize(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(v) {
+			t.Fatalf("size of %s is %d, expected %d", k, size, len(v))
+		}
+	}
+
+	// values are bound to their key
+	raw, err := child.Get(ctx, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Put(ctx, existing, raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(ctx, existing); err == nil {
+		t.Fatal("expected an error decrypting a value moved to another key")
+	}
+}
+
+func TestQueryHidesMetadata(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDatastore(t, newChild(), "secret")
+	if err := d.Put(ctx, ds.NewKey("meta/version"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	results, err := d.Query(ctx, query.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "/meta/version" || string(entries[0].Value) != "1" {
+		t.Fatalf("query returned %v, expected only /meta/version", entries)
+	}
+}
@@ -0,0 +1,216 @@
+// Package files is a datastore that stores each value in a plain file. All
+// files are kept in a single directory, so that the datastore can be read from
+// a flat mount such as a Kubernetes Secret volume. Keys are escaped to file
+// names that only contain letters, digits, "-", "_" and ".", so the key
+// "/foo/bar" is stored in the file "foo.2fbar.data" in the root directory.
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// Extension is the file extension of files that store values.
+const Extension = ".data"
+
+// Datastore stores values in files in a root directory.
+type Datastore struct {
+	root string
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+// NewDatastore creates a datastore that stores files in the root directory,
+// which must exist. The directory may be read-only if the datastore is only
+// read from.
+func NewDatastore(root string) (*Datastore, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("opening datastore directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", root)
+	}
+	return &Datastore{root: root}, nil
+}
+
+func (d *Datastore) path(key ds.Key) string {
+	return filepath.Join(d.root, escape(key)+Extension)
+}
+
+// escape returns the file name, without extension, of the file that stores the
+// value for the key. Bytes other than letters, digits, "-" and "_" are escaped
+// as "." followed by two hex digits, and the leading "/" is removed.
+func escape(key ds.Key) string {
+	s := strings.TrimPrefix(key.String(), "/")
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, ".%02x", c)
+	}
+	return b.String()
+}
+
+// unescape returns the key for a file name returned by escape.
+func unescape(name string) (ds.Key, error) {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i+2 >= len(name) {
+			return ds.Key{}, fmt.Errorf("invalid escape in file name: %s", name)
+		}
+		c, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+		if err != nil {
+			return ds.Key{}, fmt.Errorf("invalid escape in file name: %s", name)
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return ds.RawKey(b.String()), nil
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	b, err := os.ReadFile(d.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ds.ErrNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	_, err := os.Stat(d.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	info, err := os.Stat(d.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return -1, ds.ErrNotFound
+		}
+		return -1, err
+	}
+	return int(info.Size()), nil
+}
+
+// Put writes the value to a temporary file and renames it into place, so that
+// readers never see a partially written value.
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	f, err := os.CreateTemp(d.root, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+	return os.Rename(f.Name(), d.path(key))
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d *Datastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	ents, err := os.ReadDir(d.root)
+	if err != nil {
+		return nil, fmt.Errorf("reading datastore directory: %w", err)
+	}
+
+	prefix := ds.NewKey(q.Prefix).String()
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	var entries []query.Entry
+	for _, ent := range ents {
+		// hidden files include temporary files, and the directories and links
+		// Kubernetes adds to volumes
+		name, ok := strings.CutSuffix(ent.Name(), Extension)
+		if !ok || strings.HasPrefix(name, ".") {
+			continue
+		}
+		key, err := unescape(name)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(key.String(), prefix) {
+			continue
+		}
+
+		// files in a mounted volume may be links, so follow them
+		p := filepath.Join(d.root, ent.Name())
+		entry := query.Entry{Key: key.String()}
+		if q.KeysOnly {
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			entry.Size = int(info.Size())
+		} else {
+			entry.Value, err = os.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			entry.Size = len(entry.Value)
+		}
+		entries = append(entries, entry)
+	}
+
+	// the prefix has been applied, filters, orders, offset and limit have not
+	naive := q
+	naive.Prefix = ""
+	return query.NaiveQueryApply(naive, query.ResultsWithEntries(q, entries)), nil
+}
+
+// Sync is a no-op, values are written to disk when they are put.
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	return nil
+}
+
+func (d *Datastore) Close() error {
+	return nil
+}
+
+// Batch returns a batch that applies its operations one by one when committed.
+// The batch is not atomic: if a commit is interrupted, some of the operations
+// may have been applied and others not. Each value is still written atomically.
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return ds.NewBasicBatch(d), nil
+}
@@ -0,0 +1,184 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alanshaw/buff/pkg/testutil"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+func newTestDatastore(t *testing.T) (*Datastore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	d, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, dir
+}
+
+func TestSuite(t *testing.T) {
+	d, _ := newTestDatastore(t)
+	testutil.SubtestDatastore(t, d)
+}
+
+func TestEscape(t *testing.T) {
+	testCases := []struct {
+		key  string
+		name string
+	}{
+		{"/foo", "foo"},
+		{"/foo/bar", "foo.2fbar"},
+		{"/Foo-Bar_1", "Foo-Bar_1"},
+		{"/did:key:z6Mk/~blob~add/null", "did.3akey.3az6Mk.2f.7eblob.7eadd.2fnull"},
+		{"/.hidden", ".2ehidden"},
+		{"/a.data", "a.2edata"},
+		{"/ü", ".c3.bc"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			name := escape(ds.RawKey(tc.key))
+			if name != tc.name {
+				t.Fatalf("escaped to %q, expected %q", name, tc.name)
+			}
+			key, err := unescape(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.String() != tc.key {
+				t.Fatalf("unescaped to %q, expected %q", key, tc.key)
+			}
+		})
+	}
+
+	for _, name := range []string{"foo.", "foo.2", "foo.zz"} {
+		if _, err := unescape(name); err == nil {
+			t.Fatalf("expected an error unescaping %q", name)
+		}
+	}
+}
+
+func TestQuerySkipsOtherFiles(t *testing.T) {
+	ctx := context.Background()
+	d, dir := newTestDatastore(t)
+	if err := d.Put(ctx, ds.NewKey("foo"), []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a temporary file left by an interrupted put, a file without the data
+	// extension, and the hidden directory and link Kubernetes adds to volumes
+	for _, name := range []string{".tmp-123", "README", ".." + "2024_01_01" + Extension} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := queryKeys(t, d, query.Query{})
+	if len(keys) != 1 || keys[0] != "/foo" {
+		t.Fatalf("query found %v, expected [/foo]", keys)
+	}
+}
+
+func TestQueryFollowsLinks(t *testing.T) {
+	ctx := context.Background()
+	d, dir := newTestDatastore(t)
+
+	// Kubernetes mounts each file of a volume as a link into a hidden directory
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := escape(ds.NewKey("foo/bar")) + Extension
+	if err := os.WriteFile(filepath.Join(dir, "..data", name), []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := d.Get(ctx, ds.NewKey("foo/bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "bar" {
+		t.Fatalf("got %q, expected %q", v, "bar")
+	}
+	for _, keysOnly := range []bool{false, true} {
+		keys := queryKeys(t, d, query.Query{KeysOnly: keysOnly})
+		if len(keys) != 1 || keys[0] != "/foo/bar" {
+			t.Fatalf("query (keys only: %t) found %v, expected [/foo/bar]", keysOnly, keys)
+		}
+	}
+}
+
+func TestQueryPrefix(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDatastore(t)
+	for _, k := range []string{"/foo", "/foo/bar", "/foo/bar/baz", "/foobar", "/foobar/baz"} {
+		if err := d.Put(ctx, ds.NewKey(k), []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"", []string{"/foo", "/foo/bar", "/foo/bar/baz", "/foobar", "/foobar/baz"}},
+		{"/", []string{"/foo", "/foo/bar", "/foo/bar/baz", "/foobar", "/foobar/baz"}},
+		{"/foo", []string{"/foo/bar", "/foo/bar/baz"}},
+		{"/foo/", []string{"/foo/bar", "/foo/bar/baz"}},
+		{"/foobar", []string{"/foobar/baz"}},
+		{"/baz", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			keys := queryKeys(t, d, query.Query{Prefix: tc.prefix, Orders: []query.Order{query.OrderByKey{}}})
+			if len(keys) != len(tc.expected) {
+				t.Fatalf("query found %v, expected %v", keys, tc.expected)
+			}
+			for i := range keys {
+				if keys[i] != tc.expected[i] {
+					t.Fatalf("query found %v, expected %v", keys, tc.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDatastore(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewDatastore(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDatastore(file); err == nil {
+		t.Fatal("expected an error for a file")
+	}
+}
+
+func queryKeys(t *testing.T, d *Datastore, q query.Query) []string {
+	t.Helper()
+	results, err := d.Query(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"iter"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/alanshaw/buff/pkg/store"
 	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/command"
 	"github.com/alanshaw/ucantone/ucan/delegation"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
@@ -17,26 +21,49 @@ import (
 
 var log = logging.Logger("pkg/store/delegation")
 
+// Each delegation is stored under its link, and indexed by a query key
+// (aud/cmd/sub/link), a subject key and an issuer key. Keys for other records
+// use one of the following prefixes, which cannot clash with a DID.
+const (
+	// revocationPrefix is the key prefix for revocation records.
+	revocationPrefix = "revocation"
+	// issuedPrefix is the key prefix for delegations issued by the current
+	// identity, which are not indexed and never used as proofs.
+	issuedPrefix  = "issued"
+	subjectPrefix = "subject"
+	issuerPrefix  = "issuer"
+)
+
+// versionKey records the version of the key layout.
+var versionKey = datastore.NewKey("meta/version")
+
+// layoutVersion is the current version of the key layout. Version 1 added the
+// subject and issuer keys.
+const layoutVersion = 1
+
 type DSDelegationStore struct {
-	ds datastore.Datastore
+	ds datastore.Batching
 }
 
-func NewDSDelegationStore(dstore datastore.Datastore) *DSDelegationStore {
+func NewDSDelegationStore(dstore datastore.Batching) *DSDelegationStore {
 	return &DSDelegationStore{dstore}
 }
 
 func (d *DSDelegationStore) Del(ctx context.Context, root ucan.Link) error {
 	dlg, err := d.Get(ctx, root)
 	if err != nil {
-		if errors.Is(err, datastore.ErrNotFound) {
-			return store.ErrNotFound
-		}
 		return err
 	}
-	if err := d.ds.Delete(ctx, queryKey(dlg)); err != nil {
-		return err
+	batch, err := d.ds.Batch(ctx)
+	if err != nil {
+		return fmt.Errorf("creating batch: %w", err)
 	}
-	return d.ds.Delete(ctx, datastore.NewKey(root.String()))
+	for _, k := range keys(dlg) {
+		if err := batch.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(ctx)
 }
 
 func (d *DSDelegationStore) Get(ctx context.Context, root ucan.Link) (ucan.Delegation, error) {
@@ -53,13 +80,33 @@ func (d *DSDelegationStore) Get(ctx context.Context, root ucan.Link) (ucan.Deleg
 func (d *DSDelegationStore) List(ctx context.Context, aud ucan.Principal) iter.Seq2[ucan.Delegation, error] {
 	log := log.With("aud", aud.DID().String())
 	log.Infof("listing delegations")
+	return d.query(ctx, fmt.Sprintf("%s/", aud.DID().String()))
+}
+
+// FindBySubject finds delegations for the subject, or powerline delegations if
+// the subject is nil. Expired and revoked delegations are included.
+func (d *DSDelegationStore) FindBySubject(ctx context.Context, sub ucan.Subject) iter.Seq2[ucan.Delegation, error] {
+	s := subjectString(sub)
+	log.With("sub", s).Infof("finding delegations by subject")
+	return d.query(ctx, fmt.Sprintf("%s/%s/", subjectPrefix, s))
+}
+
+// FindByIssuer finds delegations issued by the issuer. Expired and revoked
+// delegations are included.
+func (d *DSDelegationStore) FindByIssuer(ctx context.Context, iss ucan.Principal) iter.Seq2[ucan.Delegation, error] {
+	log.With("iss", iss.DID().String()).Infof("finding delegations by issuer")
+	return d.query(ctx, fmt.Sprintf("%s/%s/", issuerPrefix, iss.DID().String()))
+}
+
+// query decodes the delegations stored under keys with the passed prefix.
+func (d *DSDelegationStore) query(ctx context.Context, pfx string) iter.Seq2[ucan.Delegation, error] {
 	return func(yield func(ucan.Delegation, error) bool) {
-		pfx := fmt.Sprintf("%s/", aud.DID().String())
 		results, err := d.ds.Query(ctx, query.Query{Prefix: datastore.NewKey(pfx).String()})
 		if err != nil {
 			yield(nil, fmt.Errorf("querying datastore: %w", err))
 			return
 		}
+		defer results.Close()
 		for entry := range results.Next() {
 			if entry.Error != nil {
 				yield(nil, fmt.Errorf("iterating query results: %w", entry.Error))
@@ -77,28 +124,201 @@ func (d *DSDelegationStore) List(ctx context.Context, aud ucan.Principal) iter.S
 	}
 }
 
+// FindByAudienceCommandSubject finds delegations to the audience that can
+// prove the command for the subject. The command hierarchy is walked from the
+// most specific command to top (e.g. /blob/add, /blob, /) and, at each level,
+// delegations for the subject are followed by powerline delegations. Only
+// powerline delegations are found if the subject is nil.
 func (d *DSDelegationStore) FindByAudienceCommandSubject(ctx context.Context, aud ucan.Principal, cmd ucan.Command, sub ucan.Subject) iter.Seq2[ucan.Delegation, error] {
 	log := log.With("aud", aud.DID().String(), "cmd", cmd)
 	if sub != nil {
 		log = log.With("sub", sub.DID().String())
 	}
 	log.Infof("finding delegations")
-	return func(yield func(ucan.Delegation, error) bool) {
-		pfx := fmt.Sprintf("%s/%s/", aud.DID().String(), sanitizeCommand(cmd))
+
+	var prefixes []string
+	for _, c := range commandHierarchy(cmd) {
+		pfx := fmt.Sprintf("%s/%s/", aud.DID().String(), sanitizeCommand(c))
 		if sub != nil {
-			pfx = fmt.Sprintf("%s%s/", pfx, sub.DID().String())
+			prefixes = append(prefixes, fmt.Sprintf("%s%s/", pfx, sub.DID().String()))
 		}
+		prefixes = append(prefixes, fmt.Sprintf("%s%s/", pfx, NullSubject))
+	}
 
-		results, err := d.ds.Query(ctx, query.Query{Prefix: datastore.NewKey(pfx).String()})
+	return func(yield func(ucan.Delegation, error) bool) {
+		for _, pfx := range prefixes {
+			if !d.findValid(ctx, pfx, yield) {
+				return
+			}
+		}
+	}
+}
+
+// Match implements [ucanlib.DelegationMatcher]. The store walks the command
+// hierarchy itself, so a matcher that queries each command in turn is not
+// required.
+func (d *DSDelegationStore) Match(ctx context.Context, aud ucan.Principal, cmd ucan.Command, sub ucan.Principal) iter.Seq2[ucan.Delegation, error] {
+	return d.FindByAudienceCommandSubject(ctx, aud, cmd, sub)
+}
+
+// findValid yields the delegations stored under keys with the passed prefix
+// that can currently be used as proofs. It returns false if iteration should
+// stop.
+func (d *DSDelegationStore) findValid(ctx context.Context, pfx string, yield func(ucan.Delegation, error) bool) bool {
+	for dlg, err := range d.query(ctx, pfx) {
+		if err != nil {
+			yield(nil, err)
+			return false
+		}
+		// delegations outside of their validity window cannot be used as proofs
+		if ucan.IsExpired(dlg) || ucan.IsTooEarly(dlg) {
+			continue
+		}
+		// neither can revoked delegations. Since proof chains are built by
+		// finding each link in turn, this also excludes delegations whose chain
+		// contains a revoked delegation.
+		revoked, err := d.IsRevoked(ctx, dlg.Link())
+		if err != nil {
+			yield(nil, err)
+			return false
+		}
+		if revoked {
+			continue
+		}
+		if !yield(dlg, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// GC deletes expired delegations from the store and returns the number of
+// delegations that were deleted.
+func (d *DSDelegationStore) GC(ctx context.Context) (int, error) {
+	// collect the links first, deleting while iterating is not safe for all
+	// datastore implementations.
+	var expired []ucan.Link
+	for dlg, err := range d.all(ctx) {
+		if err != nil {
+			return 0, err
+		}
+		if ucan.IsExpired(dlg) {
+			expired = append(expired, dlg.Link())
+		}
+	}
+
+	for i, root := range expired {
+		if err := d.Del(ctx, root); err != nil {
+			return i, fmt.Errorf("deleting delegation %s: %w", root, err)
+		}
+	}
+	log.Infow("collected expired delegations", "count", len(expired))
+	return len(expired), nil
+}
+
+func (d *DSDelegationStore) Put(ctx context.Context, dlg ucan.Delegation) error {
+	b, err := delegation.Encode(dlg)
+	if err != nil {
+		return err
+	}
+	batch, err := d.ds.Batch(ctx)
+	if err != nil {
+		return fmt.Errorf("creating batch: %w", err)
+	}
+	for _, k := range keys(dlg) {
+		if err := batch.Put(ctx, k, b); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(ctx)
+}
+
+// Migrate updates the key layout of a store created by an earlier version.
+// Nothing is written if the store is empty. A store that cannot be written to,
+// such as one on a read-only mount, is left as it is with a warning, since
+// finding proofs only needs the keys of the earliest layout.
+func (d *DSDelegationStore) Migrate(ctx context.Context) error {
+	version := 0
+	b, err := d.ds.Get(ctx, versionKey)
+	if err == nil {
+		version, err = strconv.Atoi(string(b))
+		if err != nil {
+			return fmt.Errorf("parsing layout version: %w", err)
+		}
+	} else if !errors.Is(err, datastore.ErrNotFound) {
+		return fmt.Errorf("getting layout version: %w", err)
+	}
+
+	if version > layoutVersion {
+		return fmt.Errorf("unsupported layout version: %d, expected %d or lower", version, layoutVersion)
+	}
+	if version == layoutVersion {
+		return nil
+	}
+
+	log.Infow("migrating delegation store", "from", version, "to", layoutVersion)
+	batch, err := d.ds.Batch(ctx)
+	if err != nil {
+		return fmt.Errorf("creating batch: %w", err)
+	}
+	// version 0 to 1: add the subject and issuer keys
+	empty := true
+	for dlg, err := range d.all(ctx) {
+		if err != nil {
+			return err
+		}
+		empty = false
+		b, err := delegation.Encode(dlg)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(ctx, subjectKey(dlg), b); err != nil {
+			return err
+		}
+		if err := batch.Put(ctx, issuerKey(dlg), b); err != nil {
+			return err
+		}
+	}
+	if empty {
+		return nil
+	}
+	if err := batch.Put(ctx, versionKey, []byte(strconv.Itoa(layoutVersion))); err != nil {
+		return err
+	}
+	if err := batch.Commit(ctx); err != nil {
+		if isReadOnly(err) {
+			log.Warnw("delegation store is read-only, not migrating", "error", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// isReadOnly returns true if the error is caused by the datastore being
+// read-only.
+func isReadOnly(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// all iterates every delegation in the store.
+func (d *DSDelegationStore) all(ctx context.Context) iter.Seq2[ucan.Delegation, error] {
+	return func(yield func(ucan.Delegation, error) bool) {
+		results, err := d.ds.Query(ctx, query.Query{})
 		if err != nil {
 			yield(nil, fmt.Errorf("querying datastore: %w", err))
 			return
 		}
+		defer results.Close()
 		for entry := range results.Next() {
 			if entry.Error != nil {
 				yield(nil, fmt.Errorf("iterating query results: %w", entry.Error))
 				return
 			}
+			// only consider the link keys, every delegation also has index keys
+			if len(datastore.NewKey(entry.Key).Namespaces()) != 1 {
+				continue
+			}
 			dlg, err := delegation.Decode(entry.Value)
 			if err != nil {
 				yield(nil, fmt.Errorf("decoding delegation: %w", err))
@@ -111,32 +331,103 @@ func (d *DSDelegationStore) FindByAudienceCommandSubject(ctx context.Context, au
 	}
 }
 
-func (d *DSDelegationStore) Put(ctx context.Context, dlg ucan.Delegation) error {
+// PutIssued records a delegation issued to another principal, so that it can
+// be revoked later.
+func (d *DSDelegationStore) PutIssued(ctx context.Context, dlg ucan.Delegation) error {
 	b, err := delegation.Encode(dlg)
 	if err != nil {
 		return err
 	}
-	if err := d.ds.Put(ctx, datastore.NewKey(dlg.Link().String()), b); err != nil {
-		return err
+	return d.ds.Put(ctx, issuedKey(dlg.Link()), b)
+}
+
+func (d *DSDelegationStore) GetIssued(ctx context.Context, root ucan.Link) (ucan.Delegation, error) {
+	b, err := d.ds.Get(ctx, issuedKey(root))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	return delegation.Decode(b)
+}
+
+// ListIssued lists the delegations recorded by PutIssued.
+func (d *DSDelegationStore) ListIssued(ctx context.Context) iter.Seq2[ucan.Delegation, error] {
+	log.Infof("listing issued delegations")
+	return d.query(ctx, fmt.Sprintf("%s/", issuedPrefix))
+}
+
+// Revoke records that the delegation has been revoked. Revocations are kept
+// even if the delegation is not in the store, so that it is not used if it is
+// added later.
+func (d *DSDelegationStore) Revoke(ctx context.Context, root ucan.Link) error {
+	return d.ds.Put(ctx, revocationKey(root), []byte{})
+}
+
+func (d *DSDelegationStore) IsRevoked(ctx context.Context, root ucan.Link) (bool, error) {
+	revoked, err := d.ds.Has(ctx, revocationKey(root))
+	if err != nil {
+		return false, fmt.Errorf("checking revocation: %w", err)
 	}
-	return d.ds.Put(ctx, queryKey(dlg), b)
+	return revoked, nil
 }
 
 var _ Store = (*DSDelegationStore)(nil)
 
+func revocationKey(root ucan.Link) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%s/%s", revocationPrefix, root.String()))
+}
+
+func issuedKey(root ucan.Link) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%s/%s", issuedPrefix, root.String()))
+}
+
+// keys returns all the keys the delegation is stored under.
+func keys(d ucan.Delegation) []datastore.Key {
+	return []datastore.Key{
+		datastore.NewKey(d.Link().String()),
+		queryKey(d),
+		subjectKey(d),
+		issuerKey(d),
+	}
+}
+
 func queryKey(d ucan.Delegation) datastore.Key {
 	aud := d.Audience().DID().String()
 	cmd := sanitizeCommand(d.Command())
-	var sub string
-	if d.Subject() == nil {
-		sub = NullSubject // powerline delegation
-	} else {
-		sub = d.Subject().DID().String()
-	}
-	key := fmt.Sprintf("%s/%s/%s/%s", aud, cmd, sub, d.Link().String())
+	key := fmt.Sprintf("%s/%s/%s/%s", aud, cmd, subjectString(d.Subject()), d.Link().String())
+	return datastore.NewKey(key)
+}
+
+func subjectKey(d ucan.Delegation) datastore.Key {
+	key := fmt.Sprintf("%s/%s/%s", subjectPrefix, subjectString(d.Subject()), d.Link().String())
 	return datastore.NewKey(key)
 }
 
+func issuerKey(d ucan.Delegation) datastore.Key {
+	key := fmt.Sprintf("%s/%s/%s", issuerPrefix, d.Issuer().DID().String(), d.Link().String())
+	return datastore.NewKey(key)
+}
+
+func subjectString(sub ucan.Subject) string {
+	if sub == nil {
+		return NullSubject // powerline delegation
+	}
+	return sub.DID().String()
+}
+
+// commandHierarchy returns the command followed by each of its parents, ending
+// with top e.g. /blob/add, /blob, /.
+func commandHierarchy(cmd ucan.Command) []ucan.Command {
+	segs := cmd.Segments()
+	cmds := make([]ucan.Command, 0, len(segs)+1)
+	for i := len(segs); i > 0; i-- {
+		cmds = append(cmds, command.New(segs[:i]...))
+	}
+	return append(cmds, command.Top())
+}
+
 func sanitizeCommand(cmd ucan.Command) string {
 	return strings.ReplaceAll(string(cmd), "/", "~")
 }
@@ -0,0 +1,490 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alanshaw/buff/pkg/store"
+	ucanlib "github.com/alanshaw/libracha/ucan"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/command"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+func newTestStore(t *testing.T) (*DSDelegationStore, datastore.Batching) {
+	t.Helper()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	return NewDSDelegationStore(ds), ds
+}
+
+func generate(t *testing.T) principal.Signer {
+	t.Helper()
+	s, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustParseCommand(t *testing.T, s string) ucan.Command {
+	t.Helper()
+	cmd, err := command.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// delegate creates a delegation from iss to aud for the command and subject,
+// which is a powerline delegation if sub is nil.
+func delegate(t *testing.T, iss principal.Signer, aud ucan.Principal, sub ucan.Subject, cmd string, options ...delegation.Option) ucan.Delegation {
+	t.Helper()
+	if len(options) == 0 {
+		options = []delegation.Option{delegation.WithNoExpiration()}
+	}
+	dlg, err := delegation.Delegate(iss, aud.DID(), sub, mustParseCommand(t, cmd), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dlg
+}
+
+func put(t *testing.T, s *DSDelegationStore, dlgs ...ucan.Delegation) {
+	t.Helper()
+	for _, dlg := range dlgs {
+		if err := s.Put(context.Background(), dlg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collect(t *testing.T, seq func(yield func(ucan.Delegation, error) bool)) []string {
+	t.Helper()
+	var links []string
+	for dlg, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, dlg.Link().String())
+	}
+	return links
+}
+
+func assertLinks(t *testing.T, actual []string, expected ...ucan.Delegation) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("found %d delegations %v, expected %d", len(actual), actual, len(expected))
+	}
+	for i, dlg := range expected {
+		if actual[i] != dlg.Link().String() {
+			t.Fatalf("delegation %d is %s, expected %s", i, actual[i], dlg.Link())
+		}
+	}
+}
+
+func TestFindByAudienceCommandSubject(t *testing.T) {
+	alice := generate(t)
+	space := generate(t)
+	other := generate(t)
+
+	exact := delegate(t, space, alice, space, "/blob/add")
+	parent := delegate(t, space, alice, space, "/blob")
+	top := delegate(t, space, alice, space, "/")
+	powerline := delegate(t, other, alice, nil, "/")
+	powerlineBlob := delegate(t, other, alice, nil, "/blob")
+	otherSubject := delegate(t, other, alice, other, "/")
+	otherCommand := delegate(t, space, alice, space, "/upload/add")
+	sibling := delegate(t, space, alice, space, "/blobs")
+	expired := delegate(t, space, alice, space, "/blob/add", delegation.WithExpiration(ucan.UTCUnixTimestamp(time.Now().Add(-time.Hour).Unix())))
+
+	testCases := []struct {
+		name     string
+		stored   []ucan.Delegation
+		cmd      string
+		sub      ucan.Subject
+		expected []ucan.Delegation
+	}{
+		{
+			name:     "exact",
+			stored:   []ucan.Delegation{exact, otherCommand, otherSubject},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: []ucan.Delegation{exact},
+		},
+		{
+			name:     "top",
+			stored:   []ucan.Delegation{top},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: []ucan.Delegation{top},
+		},
+		{
+			name:     "powerline",
+			stored:   []ucan.Delegation{powerline},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: []ucan.Delegation{powerline},
+		},
+		{
+			name:     "most specific first",
+			stored:   []ucan.Delegation{powerline, top, powerlineBlob, parent, exact},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: []ucan.Delegation{exact, parent, powerlineBlob, top, powerline},
+		},
+		{
+			name:     "only powerline without subject",
+			stored:   []ucan.Delegation{top, powerline},
+			cmd:      "/blob/add",
+			expected: []ucan.Delegation{powerline},
+		},
+		{
+			name:     "not a sibling command",
+			stored:   []ucan.Delegation{sibling},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: nil,
+		},
+		{
+			name:     "not expired",
+			stored:   []ucan.Delegation{expired},
+			cmd:      "/blob/add",
+			sub:      space,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			put(t, s, tc.stored...)
+			found := collect(t, s.FindByAudienceCommandSubject(context.Background(), alice, mustParseCommand(t, tc.cmd), tc.sub))
+			assertLinks(t, found, tc.expected...)
+		})
+	}
+}
+
+func TestFindByAudienceCommandSubjectRevoked(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	space := generate(t)
+	s, _ := newTestStore(t)
+
+	dlg := delegate(t, space, alice, space, "/")
+	put(t, s, dlg)
+	if err := s.Revoke(ctx, dlg.Link()); err != nil {
+		t.Fatal(err)
+	}
+	assertLinks(t, collect(t, s.FindByAudienceCommandSubject(ctx, alice, mustParseCommand(t, "/blob/add"), space)))
+}
+
+func TestProofChain(t *testing.T) {
+	alice := generate(t)
+	bob := generate(t)
+	space := generate(t)
+	s, _ := newTestStore(t)
+
+	// space delegates everything to alice, who delegates /blob to bob
+	root := delegate(t, space, alice, space, "/")
+	leaf := delegate(t, alice, bob, space, "/blob")
+	put(t, s, root, leaf)
+
+	// the store is used as a matcher directly, without walking the hierarchy
+	// in the caller
+	chain, _, err := ucanlib.ProofChain(context.Background(), s, bob, mustParseCommand(t, "/blob/add"), space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := make([]string, 0, len(chain))
+	for _, dlg := range chain {
+		links = append(links, dlg.Link().String())
+	}
+	assertLinks(t, links, leaf, root)
+}
+
+func TestPutGet(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	bob := generate(t)
+	space := generate(t)
+	s, _ := newTestStore(t)
+
+	toAlice := delegate(t, space, alice, space, "/")
+	toBob := delegate(t, alice, bob, space, "/blob")
+	powerline := delegate(t, alice, bob, nil, "/")
+	put(t, s, toAlice, toBob, powerline)
+
+	for _, dlg := range []ucan.Delegation{toAlice, toBob, powerline} {
+		got, err := s.Get(ctx, dlg.Link())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Link().String() != dlg.Link().String() {
+			t.Fatalf("got %s, expected %s", got.Link(), dlg.Link())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		found    func(yield func(ucan.Delegation, error) bool)
+		expected []ucan.Delegation
+	}{
+		{"list", s.List(ctx, alice), []ucan.Delegation{toAlice}},
+		{"find by subject", s.FindBySubject(ctx, space), []ucan.Delegation{toAlice, toBob}},
+		{"find by nil subject", s.FindBySubject(ctx, nil), []ucan.Delegation{powerline}},
+		{"find by issuer", s.FindByIssuer(ctx, alice), []ucan.Delegation{toBob, powerline}},
+		{"find by other issuer", s.FindByIssuer(ctx, bob), nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertLinkSet(t, collect(t, tc.found), tc.expected...)
+		})
+	}
+
+	if err := s.Del(ctx, toBob.Link()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, toBob.Link()); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected not found after delete, got: %v", err)
+	}
+	assertLinkSet(t, collect(t, s.FindBySubject(ctx, space)), toAlice)
+	assertLinkSet(t, collect(t, s.FindByIssuer(ctx, alice)), powerline)
+}
+
+func TestIssued(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	bob := generate(t)
+	s, _ := newTestStore(t)
+
+	dlg := delegate(t, alice, bob, alice, "/")
+	if err := s.PutIssued(ctx, dlg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetIssued(ctx, dlg.Link()); err != nil {
+		t.Fatal(err)
+	}
+	assertLinks(t, collect(t, s.ListIssued(ctx)), dlg)
+
+	// issued delegations are not used as proofs
+	if _, err := s.Get(ctx, dlg.Link()); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected issued delegation to be kept apart, got: %v", err)
+	}
+	assertLinks(t, collect(t, s.FindByAudienceCommandSubject(ctx, bob, dlg.Command(), alice)))
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	space := generate(t)
+	dlg := delegate(t, space, alice, space, "/")
+
+	t.Run("from version 0", func(t *testing.T) {
+		s, ds := newTestStore(t)
+		// version 0 stored the link key and the query key only
+		for _, k := range keys(dlg)[:2] {
+			mustPut(t, ds, k, encode(t, dlg))
+		}
+
+		if err := s.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assertLinks(t, collect(t, s.FindBySubject(ctx, space)), dlg)
+		assertLinks(t, collect(t, s.FindByIssuer(ctx, space)), dlg)
+		assertVersion(t, ds, "1")
+
+		report, err := s.Fsck(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.OK() {
+			t.Fatalf("store is inconsistent after migration: %+v", report)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		s, ds := newTestStore(t)
+		if err := s.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, ds, "")
+	})
+
+	t.Run("current", func(t *testing.T) {
+		s, ds := newTestStore(t)
+		put(t, s, dlg)
+		mustPut(t, ds, versionKey, []byte("1"))
+		if err := s.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, ds, "1")
+	})
+
+	t.Run("newer", func(t *testing.T) {
+		s, ds := newTestStore(t)
+		mustPut(t, ds, versionKey, []byte("2"))
+		if err := s.Migrate(ctx); err == nil {
+			t.Fatal("expected an error migrating a newer layout")
+		}
+	})
+}
+
+func assertVersion(t *testing.T, ds datastore.Batching, expected string) {
+	t.Helper()
+	b, err := ds.Get(context.Background(), versionKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		b, err = nil, nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Fatalf("layout version is %q, expected %q", b, expected)
+	}
+}
+
+// assertLinkSet is like assertLinks, but ignores the order.
+func assertLinkSet(t *testing.T, actual []string, expected ...ucan.Delegation) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("found %d delegations %v, expected %d", len(actual), actual, len(expected))
+	}
+	found := map[string]bool{}
+	for _, l := range actual {
+		found[l] = true
+	}
+	for _, dlg := range expected {
+		if !found[dlg.Link().String()] {
+			t.Fatalf("delegation %s not found in %v", dlg.Link(), actual)
+		}
+	}
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	space := generate(t)
+	s, ds := newTestStore(t)
+
+	hour := time.Now().Add(time.Hour)
+	valid := delegate(t, space, alice, space, "/")
+	expired := delegate(t, space, alice, space, "/blob", delegation.WithExpiration(ucan.UTCUnixTimestamp(time.Now().Add(-time.Hour).Unix())))
+	notYetValid := delegate(t, space, alice, space, "/upload", delegation.WithNotBefore(ucan.UTCUnixTimestamp(hour.Unix())), delegation.WithExpiration(ucan.UTCUnixTimestamp(hour.Add(time.Hour).Unix())))
+	put(t, s, valid, expired, notYetValid)
+
+	n, err := s.GC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("collected %d delegations, expected 1", n)
+	}
+	for _, k := range keys(expired) {
+		if has, err := ds.Has(ctx, k); err != nil || has {
+			t.Fatalf("key %s of expired delegation remains: %v", k, err)
+		}
+	}
+	// delegations that are not yet valid may be used later, so are kept
+	assertLinkSet(t, collect(t, s.FindBySubject(ctx, space)), valid, notYetValid)
+	assertLinks(t, collect(t, s.FindByAudienceCommandSubject(ctx, alice, mustParseCommand(t, "/upload/add"), space)), valid)
+
+	n, err = s.GC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("collected %d delegations again, expected 0", n)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	bob := generate(t)
+	space := generate(t)
+
+	t.Run("stored", func(t *testing.T) {
+		s, _ := newTestStore(t)
+		root := delegate(t, space, alice, space, "/")
+		leaf := delegate(t, alice, bob, space, "/")
+		put(t, s, root, leaf)
+
+		if err := s.Revoke(ctx, root.Link()); err != nil {
+			t.Fatal(err)
+		}
+		assertRevoked(t, s, root, true)
+		assertRevoked(t, s, leaf, false)
+
+		// the revoked delegation is kept, but no chain can be built through it
+		if _, err := s.Get(ctx, root.Link()); err != nil {
+			t.Fatal(err)
+		}
+		chain, _, err := ucanlib.ProofChain(ctx, s, bob, mustParseCommand(t, "/blob/add"), space)
+		if err == nil && len(chain) > 0 {
+			t.Fatalf("built a proof chain through a revoked delegation: %v", chain)
+		}
+	})
+
+	t.Run("before put", func(t *testing.T) {
+		s, _ := newTestStore(t)
+		dlg := delegate(t, space, alice, space, "/")
+		if err := s.Revoke(ctx, dlg.Link()); err != nil {
+			t.Fatal(err)
+		}
+		put(t, s, dlg)
+		assertRevoked(t, s, dlg, true)
+		assertLinks(t, collect(t, s.FindByAudienceCommandSubject(ctx, alice, dlg.Command(), space)))
+	})
+}
+
+func assertRevoked(t *testing.T, s *DSDelegationStore, dlg ucan.Delegation, expected bool) {
+	t.Helper()
+	revoked, err := s.IsRevoked(context.Background(), dlg.Link())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != expected {
+		t.Fatalf("delegation %s revoked: %t, expected %t", dlg.Link(), revoked, expected)
+	}
+}
+
+// readOnlyDatastore fails writes as a datastore on a read-only mount does.
+type readOnlyDatastore struct {
+	datastore.Batching
+}
+
+func (d readOnlyDatastore) Put(ctx context.Context, key datastore.Key, value []byte) error {
+	return &fs.PathError{Op: "open", Path: key.String(), Err: syscall.EROFS}
+}
+
+func (d readOnlyDatastore) Batch(ctx context.Context) (datastore.Batch, error) {
+	return datastore.NewBasicBatch(d), nil
+}
+
+func TestMigrateReadOnly(t *testing.T) {
+	ctx := context.Background()
+	alice := generate(t)
+	space := generate(t)
+	dlg := delegate(t, space, alice, space, "/")
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	for _, k := range keys(dlg)[:2] {
+		mustPut(t, ds, k, encode(t, dlg))
+	}
+	s := NewDSDelegationStore(readOnlyDatastore{ds})
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertVersion(t, ds, "")
+	// proofs are still found with the keys of the old layout
+	assertLinks(t, collect(t, s.FindByAudienceCommandSubject(ctx, alice, mustParseCommand(t, "/blob/add"), space)), dlg)
+}
@@ -11,9 +11,34 @@ import (
 const NullSubject = "null"
 
 type Store interface {
+	// FindByAudienceCommandSubject finds delegations that can prove the command
+	// for the subject, including delegations for parent commands and powerline
+	// delegations. Only delegations that are currently valid are found, that is,
+	// not expired, not before their "nbf" time and not revoked.
 	ucanlib.DelegationFinder
+	// Match is equivalent to FindByAudienceCommandSubject, allowing the store to
+	// be used directly with [ucanlib.ProofChain].
+	ucanlib.DelegationMatcher
 	Del(ctx context.Context, root ucan.Link) error
 	Get(ctx context.Context, root ucan.Link) (ucan.Delegation, error)
 	Put(ctx context.Context, dlg ucan.Delegation) error
 	List(ctx context.Context, aud ucan.Principal) iter.Seq2[ucan.Delegation, error]
+	// FindBySubject finds delegations for the subject, or powerline delegations
+	// if the subject is nil.
+	FindBySubject(ctx context.Context, sub ucan.Subject) iter.Seq2[ucan.Delegation, error]
+	// FindByIssuer finds delegations issued by the issuer.
+	FindByIssuer(ctx context.Context, iss ucan.Principal) iter.Seq2[ucan.Delegation, error]
+	// PutIssued records a delegation issued by the current identity, so that it
+	// can be revoked later. Issued delegations are kept apart from the
+	// delegations held as proofs, and are not found by the other methods.
+	PutIssued(ctx context.Context, dlg ucan.Delegation) error
+	GetIssued(ctx context.Context, root ucan.Link) (ucan.Delegation, error)
+	ListIssued(ctx context.Context) iter.Seq2[ucan.Delegation, error]
+	// GC deletes expired delegations and returns the number deleted.
+	GC(ctx context.Context) (int, error)
+	// Revoke records that the delegation has been revoked.
+	Revoke(ctx context.Context, root ucan.Link) error
+	IsRevoked(ctx context.Context, root ucan.Link) (bool, error)
+	// Fsck checks the consistency of the store, optionally repairing it.
+	Fsck(ctx context.Context, repair bool) (FsckReport, error)
 }
@@ -0,0 +1,167 @@
+package delegation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// FsckReport describes the inconsistencies found in a delegation store.
+type FsckReport struct {
+	// Delegations is the number of delegations checked.
+	Delegations int
+	// Corrupt are link keys whose value is not the delegation they refer to,
+	// and for which no index key holds an intact copy.
+	Corrupt []datastore.Key
+	// Restored are link keys that are corrupt or missing, but for which an
+	// index key holds an intact copy of the delegation.
+	Restored []datastore.Key
+	// Missing are index keys that should exist for a stored delegation but do
+	// not.
+	Missing []datastore.Key
+	// Dangling are index keys that refer to a delegation that is not stored.
+	Dangling []datastore.Key
+	// Stale are index keys whose value is not the delegation stored under the
+	// link key they refer to.
+	Stale []datastore.Key
+}
+
+// OK returns true if no inconsistencies were found.
+func (r FsckReport) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Restored) == 0 && len(r.Missing) == 0 && len(r.Dangling) == 0 && len(r.Stale) == 0
+}
+
+// Fsck checks every delegation is stored under its link key and all of its
+// index keys, and that every index key refers to a stored delegation and holds
+// the same bytes as its link key. A corrupt or missing link key is restored
+// from an index key that holds an intact copy of the delegation. If repair is
+// true, restored link keys and missing and stale index keys are written, and
+// dangling index keys and corrupt link keys are deleted, in a single batch.
+func (d *DSDelegationStore) Fsck(ctx context.Context, repair bool) (FsckReport, error) {
+	report := FsckReport{}
+
+	results, err := d.ds.Query(ctx, query.Query{})
+	if err != nil {
+		return report, fmt.Errorf("querying datastore: %w", err)
+	}
+	// the values of valid link keys, the index keys found and corrupt link
+	// keys, keyed by link
+	links := map[string][]byte{}
+	indexes := map[string][]query.Entry{}
+	corrupt := map[string]datastore.Key{}
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			results.Close()
+			return report, fmt.Errorf("iterating query results: %w", entry.Error)
+		}
+		key := datastore.NewKey(entry.Key)
+		switch key.List()[0] {
+		case revocationPrefix, issuedPrefix, versionKey.List()[0]:
+			continue
+		}
+
+		if len(key.Namespaces()) == 1 {
+			dlg, err := delegation.Decode(entry.Value)
+			if err != nil || dlg.Link().String() != key.Name() {
+				corrupt[key.Name()] = key
+				continue
+			}
+			links[key.Name()] = entry.Value
+			continue
+		}
+
+		// the link is the last namespace of all index keys
+		if _, err := cid.Parse(key.Name()); err != nil {
+			report.Dangling = append(report.Dangling, key)
+			continue
+		}
+		indexes[key.Name()] = append(indexes[key.Name()], entry.Entry)
+	}
+	results.Close()
+
+	// restore link keys from an index key that decodes to the same delegation
+	for link, es := range indexes {
+		if _, ok := links[link]; ok {
+			continue
+		}
+		for _, e := range es {
+			dlg, err := delegation.Decode(e.Value)
+			if err == nil && dlg.Link().String() == link {
+				links[link] = e.Value
+				report.Restored = append(report.Restored, datastore.NewKey(link))
+				delete(corrupt, link)
+				break
+			}
+		}
+	}
+	for _, k := range corrupt {
+		report.Corrupt = append(report.Corrupt, k)
+	}
+
+	for link, b := range links {
+		dlg, err := delegation.Decode(b)
+		if err != nil {
+			return report, err
+		}
+		report.Delegations++
+
+		found := map[string]struct{}{}
+		for _, e := range indexes[link] {
+			found[e.Key] = struct{}{}
+		}
+		expected := map[string]struct{}{}
+		// the first key is the link key, the rest are index keys
+		for _, k := range keys(dlg)[1:] {
+			expected[k.String()] = struct{}{}
+			if _, ok := found[k.String()]; !ok {
+				report.Missing = append(report.Missing, k)
+			}
+		}
+		for _, e := range indexes[link] {
+			// index keys for this link that do not match the delegation
+			if _, ok := expected[e.Key]; !ok {
+				report.Dangling = append(report.Dangling, datastore.NewKey(e.Key))
+				continue
+			}
+			if !bytes.Equal(e.Value, b) {
+				report.Stale = append(report.Stale, datastore.NewKey(e.Key))
+			}
+		}
+	}
+	for link, es := range indexes {
+		if _, ok := links[link]; !ok {
+			for _, e := range es {
+				report.Dangling = append(report.Dangling, datastore.NewKey(e.Key))
+			}
+		}
+	}
+
+	if !repair || report.OK() {
+		return report, nil
+	}
+
+	batch, err := d.ds.Batch(ctx)
+	if err != nil {
+		return report, fmt.Errorf("creating batch: %w", err)
+	}
+	for _, k := range append(append(report.Restored, report.Missing...), report.Stale...) {
+		if err := batch.Put(ctx, k, links[k.Name()]); err != nil {
+			return report, err
+		}
+	}
+	for _, k := range append(report.Dangling, report.Corrupt...) {
+		if err := batch.Delete(ctx, k); err != nil {
+			return report, err
+		}
+	}
+	if err := batch.Commit(ctx); err != nil {
+		return report, fmt.Errorf("committing repairs: %w", err)
+	}
+	log.Infow("repaired delegation store", "restored", len(report.Restored), "missing", len(report.Missing), "stale", len(report.Stale), "dangling", len(report.Dangling), "corrupt", len(report.Corrupt))
+	return report, nil
+}
@@ -0,0 +1,195 @@
+package delegation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/delegation"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestFsck(t *testing.T) {
+	alice := generate(t)
+	space := generate(t)
+	dlg := delegate(t, space, alice, space, "/")
+	other := delegate(t, space, alice, space, "/blob")
+	linkKey := datastore.NewKey(dlg.Link().String())
+
+	testCases := []struct {
+		name string
+		// damage modifies the store, which holds dlg and other
+		damage func(t *testing.T, ds datastore.Batching)
+		check  func(t *testing.T, r FsckReport)
+		// exists is true if dlg is expected to be stored after repair
+		exists bool
+	}{
+		{
+			name:   "ok",
+			damage: func(t *testing.T, ds datastore.Batching) {},
+			check:  func(t *testing.T, r FsckReport) {},
+			exists: true,
+		},
+		{
+			name: "corrupt link key",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustPut(t, ds, linkKey, []byte("garbage"))
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "restored", r.Restored, linkKey)
+				assertKeys(t, "corrupt", r.Corrupt)
+			},
+			exists: true,
+		},
+		{
+			name: "link key holds another delegation",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustPut(t, ds, linkKey, encode(t, other))
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "restored", r.Restored, linkKey)
+			},
+			exists: true,
+		},
+		{
+			name: "missing link key",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustDelete(t, ds, linkKey)
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "restored", r.Restored, linkKey)
+			},
+			exists: true,
+		},
+		{
+			name: "corrupt link key and index keys",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				for _, k := range keys(dlg) {
+					mustPut(t, ds, k, []byte("garbage"))
+				}
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "corrupt", r.Corrupt, linkKey)
+				assertKeys(t, "restored", r.Restored)
+				assertKeys(t, "dangling", r.Dangling, keys(dlg)[1:]...)
+			},
+			exists: false,
+		},
+		{
+			name: "missing index key",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustDelete(t, ds, subjectKey(dlg))
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "missing", r.Missing, subjectKey(dlg))
+			},
+			exists: true,
+		},
+		{
+			name: "stale index key",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustPut(t, ds, issuerKey(dlg), []byte("garbage"))
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "stale", r.Stale, issuerKey(dlg))
+			},
+			exists: true,
+		},
+		{
+			name: "dangling index key",
+			damage: func(t *testing.T, ds datastore.Batching) {
+				mustPut(t, ds, datastore.NewKey("subject/did:key:z6Mkfoo/"+other.Link().String()), encode(t, other))
+			},
+			check: func(t *testing.T, r FsckReport) {
+				assertKeys(t, "dangling", r.Dangling, datastore.NewKey("subject/did:key:z6Mkfoo/"+other.Link().String()))
+			},
+			exists: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, ds := newTestStore(t)
+			put(t, s, dlg, other)
+			tc.damage(t, ds)
+
+			report, err := s.Fsck(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, report)
+
+			// checking does not change the store
+			again, err := s.Fsck(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.OK() != report.OK() {
+				t.Fatal("check without repair changed the store")
+			}
+
+			if _, err := s.Fsck(ctx, true); err != nil {
+				t.Fatal(err)
+			}
+			report, err = s.Fsck(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.OK() {
+				t.Fatalf("store is inconsistent after repair: %+v", report)
+			}
+
+			_, err = s.Get(ctx, dlg.Link())
+			if exists := err == nil; exists != tc.exists {
+				t.Fatalf("delegation exists after repair: %t, expected %t (%v)", exists, tc.exists, err)
+			}
+			if tc.exists {
+				found := collect(t, s.FindByAudienceCommandSubject(ctx, alice, dlg.Command(), space))
+				assertLinks(t, found, dlg)
+			}
+			if _, err := s.Get(ctx, other.Link()); err != nil {
+				t.Fatalf("getting other delegation after repair: %s", err)
+			}
+		})
+	}
+}
+
+func encode(t *testing.T, dlg ucan.Delegation) []byte {
+	t.Helper()
+	b, err := delegation.Encode(dlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mustPut(t *testing.T, ds datastore.Batching, k datastore.Key, v []byte) {
+	t.Helper()
+	if err := ds.Put(context.Background(), k, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustDelete(t *testing.T, ds datastore.Batching, k datastore.Key) {
+	t.Helper()
+	if err := ds.Delete(context.Background(), k); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertKeys(t *testing.T, name string, actual []datastore.Key, expected ...datastore.Key) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("%s keys are %v, expected %v", name, actual, expected)
+	}
+	want := map[string]bool{}
+	for _, k := range expected {
+		want[k.String()] = true
+	}
+	for _, k := range actual {
+		if !want[k.String()] {
+			t.Fatalf("%s keys are %v, expected %v", name, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,1149 @@
+// Code generated by github.com/whyrusleeping/cbor-gen. DO NOT EDIT.
+
+package datamodel
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	cid "github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	xerrors "golang.org/x/xerrors"
+)
+
+var _ = xerrors.Errorf
+var _ = cid.Undef
+var _ = math.E
+var _ = sort.Sort
+
+func (t *UploadModel) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+	fieldCount := 8
+
+	if t.Index == nil {
+		fieldCount--
+	}
+
+	if _, err := cw.Write(cbg.CborEncodeMajorType(cbg.MajMap, uint64(fieldCount))); err != nil {
+		return err
+	}
+
+	// t.Root (cid.Cid) (struct)
+	if len("root") > 8192 {
+		return xerrors.Errorf("Value in field \"root\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("root"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("root")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteCid(cw, t.Root); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Root: %w", err)
+	}
+
+	// t.Blobs ([]datamodel.BlobModel) (slice)
+	if len("blobs") > 8192 {
+		return xerrors.Errorf("Value in field \"blobs\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("blobs"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("blobs")); err != nil {
+		return err
+	}
+
+	if len(t.Blobs) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Blobs was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Blobs))); err != nil {
+		return err
+	}
+	for _, v := range t.Blobs {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+
+	// t.Index (datamodel.BlobModel) (struct)
+	if t.Index != nil {
+
+		if len("index") > 8192 {
+			return xerrors.Errorf("Value in field \"index\" was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("index"))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string("index")); err != nil {
+			return err
+		}
+
+		if err := t.Index.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+
+	// t.Space (did.DID) (struct)
+	if len("space") > 8192 {
+		return xerrors.Errorf("Value in field \"space\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("space"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("space")); err != nil {
+		return err
+	}
+
+	if err := t.Space.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Created (int64) (int64)
+	if len("created") > 8192 {
+		return xerrors.Errorf("Value in field \"created\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("created"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("created")); err != nil {
+		return err
+	}
+
+	if t.Created >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Created)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Created-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Updated (int64) (int64)
+	if len("updated") > 8192 {
+		return xerrors.Errorf("Value in field \"updated\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("updated"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("updated")); err != nil {
+		return err
+	}
+
+	if t.Updated >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Updated)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Updated-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.IndexAdded (bool) (bool)
+	if len("indexAdded") > 8192 {
+		return xerrors.Errorf("Value in field \"indexAdded\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("indexAdded"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("indexAdded")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.IndexAdded); err != nil {
+		return err
+	}
+
+	// t.Registered (bool) (bool)
+	if len("registered") > 8192 {
+		return xerrors.Errorf("Value in field \"registered\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("registered"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("registered")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Registered); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *UploadModel) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = UploadModel{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("UploadModel: map struct too large (%d)", extra)
+	}
+
+	n := extra
+
+	nameBuf := make([]byte, 10)
+	for i := uint64(0); i < n; i++ {
+		nameLen, ok, err := cbg.ReadFullStringIntoBuf(cr, nameBuf, 8192)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(cr, func(cid.Cid) {}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch string(nameBuf[:nameLen]) {
+		// t.Root (cid.Cid) (struct)
+		case "root":
+
+			{
+
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return xerrors.Errorf("failed to read cid field t.Root: %w", err)
+				}
+
+				t.Root = c
+
+			}
+			// t.Blobs ([]datamodel.BlobModel) (slice)
+		case "blobs":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > 8192 {
+				return fmt.Errorf("t.Blobs: array too large (%d)", extra)
+			}
+
+			if maj != cbg.MajArray {
+				return fmt.Errorf("expected cbor array")
+			}
+
+			if extra > 0 {
+				t.Blobs = make([]BlobModel, extra)
+			}
+
+			for i := 0; i < int(extra); i++ {
+				{
+					var maj byte
+					var extra uint64
+					var err error
+					_ = maj
+					_ = extra
+					_ = err
+
+					{
+
+						if err := t.Blobs[i].UnmarshalCBOR(cr); err != nil {
+							return xerrors.Errorf("unmarshaling t.Blobs[i]: %w", err)
+						}
+
+					}
+
+				}
+			}
+			// t.Index (datamodel.BlobModel) (struct)
+		case "index":
+
+			{
+
+				b, err := cr.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := cr.UnreadByte(); err != nil {
+						return err
+					}
+					t.Index = new(BlobModel)
+					if err := t.Index.UnmarshalCBOR(cr); err != nil {
+						return xerrors.Errorf("unmarshaling t.Index pointer: %w", err)
+					}
+				}
+
+			}
+			// t.Space (did.DID) (struct)
+		case "space":
+
+			{
+
+				if err := t.Space.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Space: %w", err)
+				}
+
+			}
+			// t.Created (int64) (int64)
+		case "created":
+			{
+				maj, extra, err := cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				var extraI int64
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative overflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Created = int64(extraI)
+			}
+			// t.Updated (int64) (int64)
+		case "updated":
+			{
+				maj, extra, err := cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				var extraI int64
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative overflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Updated = int64(extraI)
+			}
+			// t.IndexAdded (bool) (bool)
+		case "indexAdded":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.IndexAdded = false
+			case 21:
+				t.IndexAdded = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Registered (bool) (bool)
+		case "registered":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Registered = false
+			case 21:
+				t.Registered = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(r, func(cid.Cid) {}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+func (t *BlobModel) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+	fieldCount := 11
+
+	if t.SiteTask == nil {
+		fieldCount--
+	}
+
+	if t.PutInvocation == nil {
+		fieldCount--
+	}
+
+	if t.Address == nil {
+		fieldCount--
+	}
+
+	if _, err := cw.Write(cbg.CborEncodeMajorType(cbg.MajMap, uint64(fieldCount))); err != nil {
+		return err
+	}
+
+	// t.Put (bool) (bool)
+	if len("put") > 8192 {
+		return xerrors.Errorf("Value in field \"put\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("put"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("put")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Put); err != nil {
+		return err
+	}
+
+	// t.Path (string) (string)
+	if len("path") > 8192 {
+		return xerrors.Errorf("Value in field \"path\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("path"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("path")); err != nil {
+		return err
+	}
+
+	if len(t.Path) > 8192 {
+		return xerrors.Errorf("Value in field t.Path was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Path))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string(t.Path)); err != nil {
+		return err
+	}
+
+	// t.Size (uint64) (uint64)
+	if len("size") > 8192 {
+		return xerrors.Errorf("Value in field \"size\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("size"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("size")); err != nil {
+		return err
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Size)); err != nil {
+		return err
+	}
+
+	// t.Digest ([]uint8) (slice)
+	if len("digest") > 8192 {
+		return xerrors.Errorf("Value in field \"digest\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("digest"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("digest")); err != nil {
+		return err
+	}
+
+	if len(t.Digest) > 2097152 {
+		return xerrors.Errorf("Byte array in field t.Digest was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Digest))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Digest); err != nil {
+		return err
+	}
+
+	// t.Staged (bool) (bool)
+	if len("staged") > 8192 {
+		return xerrors.Errorf("Value in field \"staged\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("staged"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("staged")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Staged); err != nil {
+		return err
+	}
+
+	// t.Address (datamodel.AddressModel) (struct)
+	if t.Address != nil {
+
+		if len("address") > 8192 {
+			return xerrors.Errorf("Value in field \"address\" was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("address"))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string("address")); err != nil {
+			return err
+		}
+
+		if err := t.Address.MarshalCBOR(cw); err != nil {
+			return err
+		}
+	}
+
+	// t.Accepted (bool) (bool)
+	if len("accepted") > 8192 {
+		return xerrors.Errorf("Value in field \"accepted\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("accepted"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("accepted")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Accepted); err != nil {
+		return err
+	}
+
+	// t.SiteTask (cid.Cid) (struct)
+	if t.SiteTask != nil {
+
+		if len("siteTask") > 8192 {
+			return xerrors.Errorf("Value in field \"siteTask\" was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("siteTask"))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string("siteTask")); err != nil {
+			return err
+		}
+
+		if t.SiteTask == nil {
+			if _, err := cw.Write(cbg.CborNull); err != nil {
+				return err
+			}
+		} else {
+			if err := cbg.WriteCid(cw, *t.SiteTask); err != nil {
+				return xerrors.Errorf("failed to write cid field t.SiteTask: %w", err)
+			}
+		}
+
+	}
+
+	// t.Concluded (bool) (bool)
+	if len("concluded") > 8192 {
+		return xerrors.Errorf("Value in field \"concluded\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("concluded"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("concluded")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Concluded); err != nil {
+		return err
+	}
+
+	// t.Locations ([]string) (slice)
+	if len("locations") > 8192 {
+		return xerrors.Errorf("Value in field \"locations\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("locations"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("locations")); err != nil {
+		return err
+	}
+
+	if len(t.Locations) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Locations was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Locations))); err != nil {
+		return err
+	}
+	for _, v := range t.Locations {
+		if len(v) > 8192 {
+			return xerrors.Errorf("Value in field v was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(v))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string(v)); err != nil {
+			return err
+		}
+
+	}
+
+	// t.PutInvocation ([]uint8) (slice)
+	if t.PutInvocation != nil {
+
+		if len("putInvocation") > 8192 {
+			return xerrors.Errorf("Value in field \"putInvocation\" was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("putInvocation"))); err != nil {
+			return err
+		}
+		if _, err := cw.WriteString(string("putInvocation")); err != nil {
+			return err
+		}
+
+		if len(t.PutInvocation) > 2097152 {
+			return xerrors.Errorf("Byte array in field t.PutInvocation was too long")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.PutInvocation))); err != nil {
+			return err
+		}
+
+		if _, err := cw.Write(t.PutInvocation); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func (t *BlobModel) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = BlobModel{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("BlobModel: map struct too large (%d)", extra)
+	}
+
+	n := extra
+
+	nameBuf := make([]byte, 13)
+	for i := uint64(0); i < n; i++ {
+		nameLen, ok, err := cbg.ReadFullStringIntoBuf(cr, nameBuf, 8192)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(cr, func(cid.Cid) {}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch string(nameBuf[:nameLen]) {
+		// t.Put (bool) (bool)
+		case "put":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Put = false
+			case 21:
+				t.Put = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Path (string) (string)
+		case "path":
+
+			{
+				sval, err := cbg.ReadStringWithMax(cr, 8192)
+				if err != nil {
+					return err
+				}
+
+				t.Path = string(sval)
+			}
+			// t.Size (uint64) (uint64)
+		case "size":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Size = uint64(extra)
+
+			}
+			// t.Digest ([]uint8) (slice)
+		case "digest":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > 2097152 {
+				return fmt.Errorf("t.Digest: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Digest = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Digest); err != nil {
+				return err
+			}
+
+			// t.Staged (bool) (bool)
+		case "staged":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Staged = false
+			case 21:
+				t.Staged = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Address (datamodel.AddressModel) (struct)
+		case "address":
+
+			{
+
+				b, err := cr.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := cr.UnreadByte(); err != nil {
+						return err
+					}
+					t.Address = new(AddressModel)
+					if err := t.Address.UnmarshalCBOR(cr); err != nil {
+						return xerrors.Errorf("unmarshaling t.Address pointer: %w", err)
+					}
+				}
+
+			}
+			// t.Accepted (bool) (bool)
+		case "accepted":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Accepted = false
+			case 21:
+				t.Accepted = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.SiteTask (cid.Cid) (struct)
+		case "siteTask":
+
+			{
+
+				b, err := cr.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := cr.UnreadByte(); err != nil {
+						return err
+					}
+
+					c, err := cbg.ReadCid(cr)
+					if err != nil {
+						return xerrors.Errorf("failed to read cid field t.SiteTask: %w", err)
+					}
+
+					t.SiteTask = &c
+				}
+
+			}
+			// t.Concluded (bool) (bool)
+		case "concluded":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Concluded = false
+			case 21:
+				t.Concluded = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Locations ([]string) (slice)
+		case "locations":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > 8192 {
+				return fmt.Errorf("t.Locations: array too large (%d)", extra)
+			}
+
+			if maj != cbg.MajArray {
+				return fmt.Errorf("expected cbor array")
+			}
+
+			if extra > 0 {
+				t.Locations = make([]string, extra)
+			}
+
+			for i := 0; i < int(extra); i++ {
+				{
+					var maj byte
+					var extra uint64
+					var err error
+					_ = maj
+					_ = extra
+					_ = err
+
+					{
+						sval, err := cbg.ReadStringWithMax(cr, 8192)
+						if err != nil {
+							return err
+						}
+
+						t.Locations[i] = string(sval)
+					}
+
+				}
+			}
+			// t.PutInvocation ([]uint8) (slice)
+		case "putInvocation":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > 2097152 {
+				return fmt.Errorf("t.PutInvocation: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.PutInvocation = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.PutInvocation); err != nil {
+				return err
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(r, func(cid.Cid) {}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+func (t *AddressModel) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{162}); err != nil {
+		return err
+	}
+
+	// t.URL (string) (string)
+	if len("url") > 8192 {
+		return xerrors.Errorf("Value in field \"url\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("url"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("url")); err != nil {
+		return err
+	}
+
+	if len(t.URL) > 8192 {
+		return xerrors.Errorf("Value in field t.URL was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.URL))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string(t.URL)); err != nil {
+		return err
+	}
+
+	// t.Headers (map[string]string) (map)
+	if len("headers") > 8192 {
+		return xerrors.Errorf("Value in field \"headers\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("headers"))); err != nil {
+		return err
+	}
+	if _, err := cw.WriteString(string("headers")); err != nil {
+		return err
+	}
+
+	{
+		if len(t.Headers) > 4096 {
+			return xerrors.Errorf("cannot marshal t.Headers map too large")
+		}
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajMap, uint64(len(t.Headers))); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(t.Headers))
+		for k := range t.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := t.Headers[k]
+
+			if len(k) > 8192 {
+				return xerrors.Errorf("Value in field k was too long")
+			}
+
+			if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(k))); err != nil {
+				return err
+			}
+			if _, err := cw.WriteString(string(k)); err != nil {
+				return err
+			}
+
+			if len(v) > 8192 {
+				return xerrors.Errorf("Value in field v was too long")
+			}
+
+			if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(v))); err != nil {
+				return err
+			}
+			if _, err := cw.WriteString(string(v)); err != nil {
+				return err
+			}
+
+		}
+	}
+	return nil
+}
+
+func (t *AddressModel) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = AddressModel{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("AddressModel: map struct too large (%d)", extra)
+	}
+
+	n := extra
+
+	nameBuf := make([]byte, 7)
+	for i := uint64(0); i < n; i++ {
+		nameLen, ok, err := cbg.ReadFullStringIntoBuf(cr, nameBuf, 8192)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(cr, func(cid.Cid) {}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch string(nameBuf[:nameLen]) {
+		// t.URL (string) (string)
+		case "url":
+
+			{
+				sval, err := cbg.ReadStringWithMax(cr, 8192)
+				if err != nil {
+					return err
+				}
+
+				t.URL = string(sval)
+			}
+			// t.Headers (map[string]string) (map)
+		case "headers":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajMap {
+				return fmt.Errorf("expected a map (major type 5)")
+			}
+			if extra > 4096 {
+				return fmt.Errorf("t.Headers: map too large")
+			}
+
+			t.Headers = make(map[string]string, extra)
+
+			for i, l := 0, int(extra); i < l; i++ {
+
+				var k string
+
+				{
+					sval, err := cbg.ReadStringWithMax(cr, 8192)
+					if err != nil {
+						return err
+					}
+
+					k = string(sval)
+				}
+
+				var v string
+
+				{
+					sval, err := cbg.ReadStringWithMax(cr, 8192)
+					if err != nil {
+						return err
+					}
+
+					v = string(sval)
+				}
+
+				t.Headers[k] = v
+
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			if err := cbg.ScanForLinks(r, func(cid.Cid) {}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
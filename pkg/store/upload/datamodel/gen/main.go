@@ -0,0 +1,16 @@
+package main
+
+import (
+	udm "github.com/alanshaw/buff/pkg/store/upload/datamodel"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func main() {
+	if err := cbg.WriteMapEncodersToFile("../cbor_gen.go", "datamodel",
+		udm.UploadModel{},
+		udm.BlobModel{},
+		udm.AddressModel{},
+	); err != nil {
+		panic(err)
+	}
+}
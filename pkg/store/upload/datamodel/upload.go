@@ -0,0 +1,45 @@
+package datamodel
+
+import (
+	"github.com/alanshaw/ucantone/did"
+	"github.com/ipfs/go-cid"
+)
+
+type UploadModel struct {
+	Root  cid.Cid `cborgen:"root"`
+	Space did.DID `cborgen:"space"`
+	// Blobs are the blobs (shards) containing the uploaded data.
+	Blobs []BlobModel `cborgen:"blobs"`
+	// Index is the blob containing the sharded DAG index, if any.
+	Index      *BlobModel `cborgen:"index,omitempty"`
+	IndexAdded bool       `cborgen:"indexAdded"`
+	Registered bool       `cborgen:"registered"`
+	Created    int64      `cborgen:"created"`
+	Updated    int64      `cborgen:"updated"`
+}
+
+type BlobModel struct {
+	Digest []byte `cborgen:"digest"`
+	Size   uint64 `cborgen:"size"`
+	// Path is the file containing the blob data.
+	Path string `cborgen:"path"`
+	// Staged indicates the file at Path was created by the upload and should be
+	// removed when the upload completes.
+	Staged bool `cborgen:"staged"`
+	// SiteTask is the /blob/accept task that will produce a location commitment.
+	SiteTask *cid.Cid `cborgen:"siteTask,omitempty"`
+	// PutInvocation is the encoded /http/put invocation.
+	PutInvocation []byte `cborgen:"putInvocation,omitempty"`
+	// Address is where the blob should be PUT, nil if the service already has
+	// the blob.
+	Address   *AddressModel `cborgen:"address,omitempty"`
+	Put       bool          `cborgen:"put"`
+	Concluded bool          `cborgen:"concluded"`
+	Accepted  bool          `cborgen:"accepted"`
+	Locations []string      `cborgen:"locations"`
+}
+
+type AddressModel struct {
+	URL     string            `cborgen:"url"`
+	Headers map[string]string `cborgen:"headers"`
+}
@@ -0,0 +1,90 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/alanshaw/buff/pkg/store"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+type DSUploadStore struct {
+	ds datastore.Datastore
+}
+
+func NewDSUploadStore(dstore datastore.Datastore) *DSUploadStore {
+	return &DSUploadStore{dstore}
+}
+
+func (d *DSUploadStore) Del(ctx context.Context, space did.DID, root cid.Cid) error {
+	k := uploadKey(space, root)
+	if has, err := d.ds.Has(ctx, k); err != nil {
+		return err
+	} else if !has {
+		return store.ErrNotFound
+	}
+	return d.ds.Delete(ctx, k)
+}
+
+func (d *DSUploadStore) Get(ctx context.Context, space did.DID, root cid.Cid) (Upload, error) {
+	b, err := d.ds.Get(ctx, uploadKey(space, root))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return Upload{}, store.ErrNotFound
+		}
+		return Upload{}, err
+	}
+	return decode(b)
+}
+
+func (d *DSUploadStore) Put(ctx context.Context, upload Upload) error {
+	var buf bytes.Buffer
+	if err := upload.MarshalCBOR(&buf); err != nil {
+		return fmt.Errorf("encoding upload: %w", err)
+	}
+	return d.ds.Put(ctx, uploadKey(upload.Space, upload.Root), buf.Bytes())
+}
+
+func (d *DSUploadStore) List(ctx context.Context) iter.Seq2[Upload, error] {
+	return func(yield func(Upload, error) bool) {
+		results, err := d.ds.Query(ctx, query.Query{})
+		if err != nil {
+			yield(Upload{}, fmt.Errorf("querying datastore: %w", err))
+			return
+		}
+		for entry := range results.Next() {
+			if entry.Error != nil {
+				yield(Upload{}, fmt.Errorf("iterating query results: %w", entry.Error))
+				return
+			}
+			u, err := decode(entry.Value)
+			if err != nil {
+				yield(Upload{}, fmt.Errorf("decoding upload: %w", err))
+				return
+			}
+			if !yield(u, nil) {
+				return
+			}
+		}
+	}
+}
+
+var _ Store = (*DSUploadStore)(nil)
+
+func uploadKey(space did.DID, root cid.Cid) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%s/%s", space.String(), root.String()))
+}
+
+func decode(b []byte) (Upload, error) {
+	u := Upload{}
+	if err := u.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return Upload{}, err
+	}
+	return u, nil
+}
@@ -0,0 +1,26 @@
+package upload
+
+import (
+	"context"
+	"iter"
+
+	udm "github.com/alanshaw/buff/pkg/store/upload/datamodel"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/ipfs/go-cid"
+)
+
+type (
+	// Upload is the journal of an in-progress upload of content to a space.
+	Upload = udm.UploadModel
+	// Blob is the state of a blob that is part of an upload.
+	Blob    = udm.BlobModel
+	Address = udm.AddressModel
+)
+
+// Store is a journal of uploads that have not yet completed.
+type Store interface {
+	Del(ctx context.Context, space did.DID, root cid.Cid) error
+	Get(ctx context.Context, space did.DID, root cid.Cid) (Upload, error)
+	Put(ctx context.Context, upload Upload) error
+	List(ctx context.Context) iter.Seq2[Upload, error]
+}
@@ -0,0 +1,60 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dstest "github.com/ipfs/go-datastore/test"
+)
+
+// datastoreSubtests are the go-datastore conformance tests run by
+// SubtestDatastore. The combinations test is left out, it writes thousands of
+// values and takes minutes against a datastore that syncs every write.
+var datastoreSubtests = []struct {
+	name string
+	test func(t *testing.T, d ds.Batching)
+}{
+	{"BasicPutGet", func(t *testing.T, d ds.Batching) { dstest.SubtestBasicPutGet(t, d) }},
+	{"NotFounds", func(t *testing.T, d ds.Batching) { dstest.SubtestNotFounds(t, d) }},
+	{"Prefix", func(t *testing.T, d ds.Batching) { dstest.SubtestPrefix(t, d) }},
+	{"Order", func(t *testing.T, d ds.Batching) { dstest.SubtestOrder(t, d) }},
+	{"Limit", func(t *testing.T, d ds.Batching) { dstest.SubtestLimit(t, d) }},
+	{"Filter", func(t *testing.T, d ds.Batching) { dstest.SubtestFilter(t, d) }},
+	{"ManyKeysAndQuery", func(t *testing.T, d ds.Batching) { dstest.SubtestManyKeysAndQuery(t, d) }},
+	{"ReturnSizes", func(t *testing.T, d ds.Batching) { dstest.SubtestReturnSizes(t, d) }},
+	{"BasicSync", func(t *testing.T, d ds.Batching) { dstest.SubtestBasicSync(t, d) }},
+	{"Batch", dstest.RunBatchTest},
+	{"BatchDelete", dstest.RunBatchDeleteTest},
+	{"BatchPutAndDelete", dstest.RunBatchPutAndDeleteTest},
+}
+
+// SubtestDatastore runs the go-datastore conformance tests against the
+// datastore, deleting every key after each test.
+func SubtestDatastore(t *testing.T, d ds.Batching) {
+	for _, st := range datastoreSubtests {
+		t.Run(st.name, func(t *testing.T) {
+			st.test(t, d)
+			clearDatastore(t, d)
+		})
+	}
+}
+
+func clearDatastore(t *testing.T, d ds.Batching) {
+	t.Helper()
+	ctx := context.Background()
+	results, err := d.Query(ctx, query.Query{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := d.Delete(ctx, ds.RawKey(e.Key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+// Package testutil provides helpers for testing code that interacts with the
+// Storacha Network.
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	index_caps "github.com/alanshaw/buff/pkg/capabilities/index"
+	revoke_caps "github.com/alanshaw/buff/pkg/capabilities/ucan"
+	upload_caps "github.com/alanshaw/buff/pkg/capabilities/upload"
+	"github.com/alanshaw/buff/pkg/config/app"
+	"github.com/alanshaw/libracha/capabilities"
+	assert_caps "github.com/alanshaw/libracha/capabilities/assert"
+	"github.com/alanshaw/libracha/capabilities/blob"
+	http_caps "github.com/alanshaw/libracha/capabilities/http"
+	ucan_caps "github.com/alanshaw/libracha/capabilities/ucan"
+	"github.com/alanshaw/libracha/digestutil"
+	"github.com/alanshaw/ucantone/did"
+	"github.com/alanshaw/ucantone/execution/bindexec"
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/alanshaw/ucantone/ipld/codec/dagcbor"
+	"github.com/alanshaw/ucantone/ipld/datamodel"
+	"github.com/alanshaw/ucantone/principal"
+	"github.com/alanshaw/ucantone/principal/ed25519"
+	"github.com/alanshaw/ucantone/result"
+	"github.com/alanshaw/ucantone/server"
+	"github.com/alanshaw/ucantone/transport"
+	"github.com/alanshaw/ucantone/ucan"
+	"github.com/alanshaw/ucantone/ucan/container"
+	"github.com/alanshaw/ucantone/ucan/invocation"
+	"github.com/alanshaw/ucantone/ucan/promise"
+	"github.com/alanshaw/ucantone/ucan/receipt"
+	"github.com/multiformats/go-multihash"
+)
+
+// checksumHeader is the header the blob provider requires on PUT requests. Its
+// value is the base64 encoded sha2-256 hash of the blob.
+const checksumHeader = "X-Amz-Checksum-Sha256"
+
+// UploadService is an in-process fake of the Storacha upload service and a
+// single blob provider. It speaks the UCAN HTTP transport and implements the
+// blob upload flow:
+//
+//   - /blob/add responds with /blob/allocate, /http/put and /blob/accept
+//     invocations, a receipt for the allocation and the blob provider's key in
+//     the /http/put invocation metadata.
+//   - Blob data is PUT to /blob/{digest} and can be fetched from the same URL.
+//   - /ucan/conclude accepts the /http/put receipt, after which the receipt for
+//     the /blob/accept task, along with its location commitment, is served from
+//     /receipt/{task}.
+//
+// /upload/add, /index/add and /ucan/revoke invocations are recorded and can be
+// inspected.
+type UploadService struct {
+	// ID is the identity of the upload service.
+	ID principal.Signer
+	// Provider is the identity of the blob provider.
+	Provider principal.Signer
+	// URL is the base URL of the service.
+	URL *url.URL
+
+	mu sync.Mutex
+	// blobs are the blobs PUT to the provider, keyed by digest.
+	blobs map[string][]byte
+	// puts are blobs awaiting an /http/put receipt, keyed by the /http/put task.
+	puts map[string]pendingPut
+	// receipts are the receipts that can be fetched, keyed by task.
+	receipts map[string]storedReceipt
+	uploads  map[string][]ucan.Link
+	indexes  map[string][]ucan.Link
+	revoked  map[string]struct{}
+}
+
+type pendingPut struct {
+	space  did.DID
+	blob   blob.Blob
+	accept ucan.Invocation
+}
+
+type storedReceipt struct {
+	receipt     ucan.Receipt
+	invocations []ucan.Invocation
+}
+
+// NewUploadService starts a fake upload service on an [httptest.Server] that
+// is closed when the test completes.
+func NewUploadService(t testing.TB) *UploadService {
+	t.Helper()
+	id, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := ed25519.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &UploadService{
+		ID:       id,
+		Provider: provider,
+		blobs:    map[string][]byte{},
+		puts:     map[string]pendingPut{},
+		receipts: map[string]storedReceipt{},
+		uploads:  map[string][]ucan.Link{},
+		indexes:  map[string][]ucan.Link{},
+		revoked:  map[string]struct{}{},
+	}
+
+	ucanServer := server.NewHTTP(id)
+	ucanServer.Handle(blob.Add, bindexec.NewHandler(s.handleBlobAdd))
+	ucanServer.Handle(ucan_caps.Conclude, bindexec.NewHandler(s.handleConclude))
+	ucanServer.Handle(upload_caps.Add, bindexec.NewHandler(s.handleUploadAdd))
+	ucanServer.Handle(index_caps.Add, bindexec.NewHandler(s.handleIndexAdd))
+	ucanServer.Handle(revoke_caps.Revoke, bindexec.NewHandler(s.handleRevoke))
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /{$}", ucanServer)
+	mux.HandleFunc("PUT /blob/{digest}", s.handlePut)
+	mux.HandleFunc("GET /blob/{digest}", s.handleGet)
+	mux.HandleFunc("GET /receipt/{task}", s.handleReceipt)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	s.URL, err = url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Config returns configuration for connecting to the upload service.
+func (s *UploadService) Config() app.ExternalServicesConfig {
+	return app.ExternalServicesConfig{
+		Upload: app.UploadServiceConfig{ID: s.ID.DID(), URL: s.URL},
+	}
+}
+
+// Blob returns the data for a blob that was PUT to the blob provider.
+func (s *UploadService) Blob(digest multihash.Multihash) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[string(digest)]
+	return data, ok
+}
+
+// Upload returns the shards registered for the root in the space by an
+// /upload/add invocation.
+func (s *UploadService) Upload(space did.DID, root ucan.Link) ([]ucan.Link, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shards, ok := s.uploads[uploadKey(space, root)]
+	return shards, ok
+}
+
+// Indexes returns the indexes added to the space by /index/add invocations.
+func (s *UploadService) Indexes(space did.DID) []ucan.Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ucan.Link{}, s.indexes[space.String()]...)
+}
+
+// Revoked returns true if the delegation was revoked by a /ucan/revoke
+// invocation.
+func (s *UploadService) Revoked(root ucan.Link) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.revoked[root.String()]
+	return ok
+}
+
+func (s *UploadService) blobURL(digest multihash.Multihash) *url.URL {
+	return s.URL.JoinPath("blob", digestutil.Format(digest))
+}
+
+func (s *UploadService) handleBlobAdd(req *bindexec.Request[*blob.AddArguments]) (*bindexec.Response[*blob.AddOK], error) {
+	inv := req.Invocation()
+	space, err := did.Parse(inv.Subject().DID().String())
+	if err != nil {
+		return bindexec.NewResponse(bindexec.WithFailure[*blob.AddOK](err))
+	}
+	args := req.Task().BindArguments()
+
+	checksum, err := sha256Checksum(args.Blob.Digest)
+	if err != nil {
+		return bindexec.NewResponse(bindexec.WithFailure[*blob.AddOK](err))
+	}
+
+	allocInv, err := blob.Allocate.Invoke(
+		s.ID,
+		space,
+		&blob.AllocateArguments{Blob: args.Blob, Cause: inv.Link()},
+		invocation.WithAudience(s.Provider),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	_, stored := s.blobs[string(args.Blob.Digest)]
+	s.mu.Unlock()
+
+	allocOK := blob.AllocateOK{Size: args.Blob.Size}
+	if !stored {
+		allocOK.Address = &blob.BlobAddress{
+			URL:     capabilities.CborURL(*s.blobURL(args.Blob.Digest)),
+			Headers: map[string]string{checksumHeader: checksum},
+			Expires: capabilities.CborTime(time.Now().Add(time.Hour)),
+		}
+	}
+	allocRcpt, err := issueOK(s.Provider, allocInv.Task().Link(), &allocOK)
+	if err != nil {
+		return nil, err
+	}
+
+	putInv, err := http_caps.Put.Invoke(
+		s.Provider,
+		s.Provider,
+		&http_caps.PutArguments{
+			Body:        args.Blob,
+			Destination: promise.AwaitOK{Task: allocInv.Task().Link()},
+		},
+		invocation.WithAudience(s.Provider),
+		invocation.WithMetadata(ipld.Map{
+			"keys": ipld.Map{s.Provider.DID().String(): s.Provider.Bytes()},
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptInv, err := blob.Accept.Invoke(
+		s.ID,
+		space,
+		&blob.AcceptArguments{
+			Blob: args.Blob,
+			Put:  promise.AwaitOK{Task: putInv.Task().Link()},
+		},
+		invocation.WithAudience(s.Provider),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := pendingPut{space: space, blob: args.Blob, accept: acceptInv}
+	if stored {
+		// the provider already has the blob so it can be accepted immediately.
+		if err := s.accept(pending); err != nil {
+			return nil, err
+		}
+	} else {
+		s.mu.Lock()
+		s.puts[putInv.Task().Link().String()] = pending
+		s.mu.Unlock()
+	}
+
+	return bindexec.NewResponse(
+		bindexec.WithSuccess(&blob.AddOK{Site: promise.AwaitOK{Task: acceptInv.Task().Link()}}),
+		bindexec.WithMetadata[*blob.AddOK](container.New(
+			container.WithInvocations(allocInv, putInv, acceptInv),
+			container.WithReceipts(allocRcpt),
+		)),
+	)
+}
+
+func (s *UploadService) handleConclude(req *bindexec.Request[*ucan_caps.ConcludeArguments]) (*bindexec.Response[*ucan_caps.ConcludeOK], error) {
+	args := req.Task().BindArguments()
+
+	var rcpt ucan.Receipt
+	for _, r := range req.Metadata().Receipts() {
+		if r.Link() == args.Receipt {
+			rcpt = r
+			break
+		}
+	}
+	if rcpt == nil {
+		return bindexec.NewResponse(bindexec.WithFailure[*ucan_caps.ConcludeOK](fmt.Errorf("missing receipt: %s", args.Receipt)))
+	}
+
+	s.mu.Lock()
+	pending, ok := s.puts[rcpt.Ran().String()]
+	s.mu.Unlock()
+	if !ok {
+		return bindexec.NewResponse(bindexec.WithFailure[*ucan_caps.ConcludeOK](fmt.Errorf("unknown task: %s", rcpt.Ran())))
+	}
+	if rcpt.Issuer().DID() != s.Provider.DID() {
+		return bindexec.NewResponse(bindexec.WithFailure[*ucan_caps.ConcludeOK](fmt.Errorf("receipt not issued by blob provider: %s", rcpt.Issuer().DID())))
+	}
+	if _, ok := s.Blob(pending.blob.Digest); !ok {
+		return bindexec.NewResponse(bindexec.WithFailure[*ucan_caps.ConcludeOK](fmt.Errorf("blob not received: %s", digestutil.Format(pending.blob.Digest))))
+	}
+
+	if err := s.accept(pending); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	delete(s.puts, rcpt.Ran().String())
+	s.mu.Unlock()
+
+	return bindexec.NewResponse(bindexec.WithSuccess(&ucan_caps.ConcludeOK{}))
+}
+
+// accept issues a location commitment for the blob and a receipt for the
+// /blob/accept task that refers to it.
+func (s *UploadService) accept(p pendingPut) error {
+	loc, err := assert_caps.Location.Invoke(
+		s.Provider,
+		s.Provider,
+		&assert_caps.LocationArguments{
+			Space:    p.space,
+			Content:  p.blob.Digest,
+			Location: []capabilities.CborURL{capabilities.CborURL(*s.blobURL(p.blob.Digest))},
+		},
+		invocation.WithAudience(p.space),
+	)
+	if err != nil {
+		return fmt.Errorf("creating location commitment: %w", err)
+	}
+
+	rcpt, err := issueOK(s.Provider, p.accept.Task().Link(), &blob.AcceptOK{Site: loc.Link()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receipts[p.accept.Task().Link().String()] = storedReceipt{
+		receipt:     rcpt,
+		invocations: []ucan.Invocation{p.accept, loc},
+	}
+	return nil
+}
+
+func (s *UploadService) handleUploadAdd(req *bindexec.Request[*upload_caps.AddArguments]) (*bindexec.Response[*upload_caps.AddOK], error) {
+	space, err := did.Parse(req.Invocation().Subject().DID().String())
+	if err != nil {
+		return bindexec.NewResponse(bindexec.WithFailure[*upload_caps.AddOK](err))
+	}
+	args := req.Task().BindArguments()
+
+	s.mu.Lock()
+	s.uploads[uploadKey(space, args.Root)] = args.Shards
+	s.mu.Unlock()
+
+	return bindexec.NewResponse(bindexec.WithSuccess(&upload_caps.AddOK{Root: args.Root, Shards: args.Shards}))
+}
+
+func (s *UploadService) handleIndexAdd(req *bindexec.Request[*index_caps.AddArguments]) (*bindexec.Response[*index_caps.AddOK], error) {
+	space := req.Invocation().Subject().DID().String()
+	args := req.Task().BindArguments()
+
+	s.mu.Lock()
+	s.indexes[space] = append(s.indexes[space], args.Index)
+	s.mu.Unlock()
+
+	return bindexec.NewResponse(bindexec.WithSuccess(&index_caps.AddOK{}))
+}
+
+func (s *UploadService) handleRevoke(req *bindexec.Request[*revoke_caps.RevokeArguments]) (*bindexec.Response[*revoke_caps.RevokeOK], error) {
+	args := req.Task().BindArguments()
+	authority := req.Invocation().Subject().DID()
+	authorized := false
+	for _, link := range append([]ucan.Link{args.UCAN}, args.Proof...) {
+		dlg, ok := req.Metadata().Delegation(link)
+		if !ok {
+			return bindexec.NewResponse(bindexec.WithFailure[*revoke_caps.RevokeOK](fmt.Errorf("missing delegation: %s", link)))
+		}
+		if dlg.Issuer().DID() == authority {
+			authorized = true
+		}
+	}
+	if !authorized {
+		return bindexec.NewResponse(bindexec.WithFailure[*revoke_caps.RevokeOK](fmt.Errorf("%s did not issue %s or its proof", authority, args.UCAN)))
+	}
+
+	s.mu.Lock()
+	s.revoked[args.UCAN.String()] = struct{}{}
+	s.mu.Unlock()
+
+	return bindexec.NewResponse(bindexec.WithSuccess(&revoke_caps.RevokeOK{}))
+}
+
+func (s *UploadService) handlePut(w http.ResponseWriter, r *http.Request) {
+	digest, err := digestutil.Parse(r.PathValue("digest"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	checksum, err := sha256Checksum(digest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get(checksumHeader) != checksum {
+		http.Error(w, "checksum header mismatch", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(data)
+	if base64.StdEncoding.EncodeToString(sum[:]) != checksum {
+		http.Error(w, "data does not match digest", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.blobs[string(digest)] = data
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *UploadService) handleGet(w http.ResponseWriter, r *http.Request) {
+	digest, err := digestutil.Parse(r.PathValue("digest"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, ok := s.Blob(digest)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+func (s *UploadService) handleReceipt(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	stored, ok := s.receipts[r.PathValue("task")]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	res, err := transport.DefaultHTTPInboundCodec.Encode(container.New(
+		container.WithReceipts(stored.receipt),
+		container.WithInvocations(stored.invocations...),
+	))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer res.Body.Close()
+	w.Header().Set("Content-Type", res.Header.Get("Content-Type"))
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+// issueOK issues a receipt for the task with the passed successful output.
+func issueOK(executor ucan.Signer, task ucan.Link, out dagcbor.Marshaler) (ucan.Receipt, error) {
+	m := datamodel.Map{}
+	if err := datamodel.Rebind(out, &m); err != nil {
+		return nil, fmt.Errorf("encoding receipt output: %w", err)
+	}
+	rcpt, err := receipt.Issue(executor, task, result.OK[datamodel.Map, ipld.Any](m))
+	if err != nil {
+		return nil, fmt.Errorf("issuing receipt: %w", err)
+	}
+	return rcpt, nil
+}
+
+func sha256Checksum(digest multihash.Multihash) (string, error) {
+	info, err := multihash.Decode(digest)
+	if err != nil {
+		return "", fmt.Errorf("decoding digest: %w", err)
+	}
+	if info.Code != multihash.SHA2_256 {
+		return "", fmt.Errorf("unsupported hash function: 0x%x", info.Code)
+	}
+	return base64.StdEncoding.EncodeToString(info.Digest), nil
+}
+
+func uploadKey(space did.DID, root ucan.Link) string {
+	return space.String() + "/" + root.String()
+}
@@ -0,0 +1,37 @@
+package unixfs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+// BuildDirectory creates a UnixFS directory node linking to the passed
+// entries and passes it to emit. Entries are sorted by name.
+func BuildDirectory(entries []Link, emit BlockEmitter) (Link, error) {
+	links := slices.Clone(entries)
+	slices.SortFunc(links, func(a, b Link) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	var tsize uint64
+	for i, l := range links {
+		if l.Name == "" {
+			return Link{}, fmt.Errorf("directory entry %d has no name", i)
+		}
+		if i > 0 && links[i-1].Name == l.Name {
+			return Link{}, fmt.Errorf("duplicate directory entry: %q", l.Name)
+		}
+		tsize += l.Tsize
+	}
+	data := encodeData(TDirectory, nil, nil, nil)
+	blk, err := newBlock(cid.DagProtobuf, encodeNode(links, data))
+	if err != nil {
+		return Link{}, err
+	}
+	if err := emit(blk); err != nil {
+		return Link{}, err
+	}
+	return Link{Cid: blk.Link(), Tsize: tsize + uint64(len(blk.Bytes()))}, nil
+}
@@ -0,0 +1,143 @@
+package unixfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+)
+
+type fileConfig struct {
+	chunkSize int
+	maxLinks  int
+}
+
+type FileOption func(cfg *fileConfig)
+
+// WithChunkSize configures the maximum size of leaf blocks. The default is
+// [DefaultChunkSize].
+func WithChunkSize(size int) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.chunkSize = size
+	}
+}
+
+// WithMaxLinks configures the maximum number of links per file node. The
+// default is [DefaultMaxLinks].
+func WithMaxLinks(n int) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.maxLinks = n
+	}
+}
+
+// fileLink is a link to a file sub-DAG along with the number of bytes of file
+// content it contains.
+type fileLink struct {
+	cid   cid.Cid
+	tsize uint64
+	size  uint64
+}
+
+// BuildFile chunks the data read from r into raw leaves and builds a balanced
+// UnixFS file DAG on top of them. Blocks are passed to emit as they are
+// created, so memory use does not depend on the size of the file. A file that
+// fits in a single chunk is represented by a single raw block.
+func BuildFile(r io.Reader, emit BlockEmitter, options ...FileOption) (Link, error) {
+	cfg := fileConfig{chunkSize: DefaultChunkSize, maxLinks: DefaultMaxLinks}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if cfg.chunkSize <= 0 {
+		return Link{}, fmt.Errorf("invalid chunk size: %d", cfg.chunkSize)
+	}
+	if cfg.maxLinks < 2 {
+		return Link{}, fmt.Errorf("invalid max links: %d", cfg.maxLinks)
+	}
+
+	// levels of the tree that are still being filled, levels[0] are leaves
+	levels := [][]fileLink{nil}
+
+	push := func(lvl int, l fileLink) error {
+		for {
+			if lvl == len(levels) {
+				levels = append(levels, nil)
+			}
+			levels[lvl] = append(levels[lvl], l)
+			if len(levels[lvl]) < cfg.maxLinks {
+				return nil
+			}
+			parent, err := buildFileNode(levels[lvl], emit)
+			if err != nil {
+				return err
+			}
+			levels[lvl] = nil
+			lvl, l = lvl+1, parent
+		}
+	}
+
+	buf := make([]byte, cfg.chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return Link{}, fmt.Errorf("reading file data: %w", err)
+		}
+		if n == 0 && !(len(levels) == 1 && len(levels[0]) == 0) {
+			break
+		}
+		leaf, berr := newBlock(cid.Raw, append([]byte(nil), buf[:n]...))
+		if berr != nil {
+			return Link{}, berr
+		}
+		if berr := emit(leaf); berr != nil {
+			return Link{}, berr
+		}
+		if berr := push(0, fileLink{cid: leaf.Link(), tsize: uint64(n), size: uint64(n)}); berr != nil {
+			return Link{}, berr
+		}
+		if err != nil {
+			break // EOF
+		}
+	}
+
+	// flush partially filled levels
+	for lvl := 0; lvl < len(levels); lvl++ {
+		links := levels[lvl]
+		if lvl == len(levels)-1 && len(links) == 1 {
+			return Link{Cid: links[0].cid, Tsize: links[0].tsize}, nil
+		}
+		if len(links) == 0 {
+			continue
+		}
+		parent, err := buildFileNode(links, emit)
+		if err != nil {
+			return Link{}, err
+		}
+		if lvl == len(levels)-1 {
+			levels = append(levels, nil)
+		}
+		levels[lvl+1] = append(levels[lvl+1], parent)
+	}
+	return Link{}, errors.New("no root node built")
+}
+
+func buildFileNode(children []fileLink, emit BlockEmitter) (fileLink, error) {
+	links := make([]Link, 0, len(children))
+	blocksizes := make([]uint64, 0, len(children))
+	var size, tsize uint64
+	for _, c := range children {
+		links = append(links, Link{Cid: c.cid, Tsize: c.tsize})
+		blocksizes = append(blocksizes, c.size)
+		size += c.size
+		tsize += c.tsize
+	}
+	data := encodeData(TFile, nil, &size, blocksizes)
+	blk, err := newBlock(cid.DagProtobuf, encodeNode(links, data))
+	if err != nil {
+		return fileLink{}, err
+	}
+	if err := emit(blk); err != nil {
+		return fileLink{}, err
+	}
+	return fileLink{cid: blk.Link(), tsize: tsize + uint64(len(blk.Bytes())), size: size}, nil
+}
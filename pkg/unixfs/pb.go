@@ -0,0 +1,152 @@
+package unixfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+// protobuf wire types used by dag-pb and UnixFS
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Link is a named, sized link from a dag-pb node to another block.
+type Link struct {
+	Name string
+	// Cid is the link to the target block.
+	Cid cid.Cid
+	// Tsize is the cumulative size of the target block and all blocks it links
+	// to (the total encoded size of the sub-DAG).
+	Tsize uint64
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// encodeNode encodes a dag-pb PBNode in canonical form (links first, then
+// data).
+func encodeNode(links []Link, data []byte) []byte {
+	var b []byte
+	for _, l := range links {
+		var lb []byte
+		lb = appendBytesField(lb, 1, l.Cid.Bytes())
+		lb = appendBytesField(lb, 2, []byte(l.Name))
+		lb = appendVarintField(lb, 3, l.Tsize)
+		b = appendBytesField(b, 2, lb)
+	}
+	if data != nil {
+		b = appendBytesField(b, 1, data)
+	}
+	return b
+}
+
+// Node is a decoded dag-pb node.
+type Node struct {
+	Links []Link
+	Data  []byte
+}
+
+// DecodeNode decodes a dag-pb encoded block.
+func DecodeNode(b []byte) (Node, error) {
+	var n Node
+	err := readFields(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			n.Data = data
+		case field == 2 && wire == wireBytes:
+			l, err := decodeLink(data)
+			if err != nil {
+				return fmt.Errorf("decoding link %d: %w", len(n.Links), err)
+			}
+			n.Links = append(n.Links, l)
+		default:
+			return fmt.Errorf("unexpected PBNode field: %d", field)
+		}
+		return nil
+	})
+	if err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+func decodeLink(b []byte) (Link, error) {
+	var l Link
+	err := readFields(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			c, err := cid.Cast(data)
+			if err != nil {
+				return fmt.Errorf("decoding hash: %w", err)
+			}
+			l.Cid = c
+		case field == 2 && wire == wireBytes:
+			l.Name = string(data)
+		case field == 3 && wire == wireVarint:
+			l.Tsize = v
+		default:
+			return fmt.Errorf("unexpected PBLink field: %d", field)
+		}
+		return nil
+	})
+	if err != nil {
+		return Link{}, err
+	}
+	if !l.Cid.Defined() {
+		return Link{}, errors.New("missing hash")
+	}
+	return l, nil
+}
+
+// readFields iterates over the fields of a protobuf message, calling fn with
+// the value for varint fields or the data for length delimited fields.
+func readFields(b []byte, fn func(field int, wire int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid field tag")
+		}
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint in field %d", field)
+			}
+			b = b[n:]
+			if err := fn(field, wire, v, nil); err != nil {
+				return err
+			}
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("invalid length in field %d", field)
+			}
+			data := b[n : n+int(l)]
+			b = b[n+int(l):]
+			if err := fn(field, wire, 0, data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package unixfs
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/alanshaw/ucantone/ipld"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// DataType is the type of a UnixFS node.
+type DataType uint64
+
+const (
+	TRaw       DataType = 0
+	TDirectory DataType = 1
+	TFile      DataType = 2
+	TMetadata  DataType = 3
+	TSymlink   DataType = 4
+	THAMTShard DataType = 5
+)
+
+const (
+	// DefaultChunkSize is the maximum number of bytes of file data stored in a
+	// single leaf block.
+	DefaultChunkSize = 1024 * 1024
+	// DefaultMaxLinks is the maximum number of links a file node may have before
+	// another layer is added to the DAG.
+	DefaultMaxLinks = 1024
+)
+
+// Block is an encoded IPLD block produced by a DAG builder.
+type Block struct {
+	cid   cid.Cid
+	bytes []byte
+}
+
+func (b Block) Link() cid.Cid {
+	return b.cid
+}
+
+func (b Block) Bytes() []byte {
+	return b.bytes
+}
+
+// BlockEmitter receives blocks as they are created by a DAG builder. Blocks
+// are always emitted before any block that links to them.
+type BlockEmitter func(blk ipld.Block) error
+
+func newBlock(codec uint64, b []byte) (Block, error) {
+	digest, err := multihash.Sum(b, multihash.SHA2_256, -1)
+	if err != nil {
+		return Block{}, err
+	}
+	return Block{cid: cid.NewCidV1(codec, digest), bytes: b}, nil
+}
+
+// encodeData encodes a UnixFS Data protobuf message.
+func encodeData(typ DataType, data []byte, filesize *uint64, blocksizes []uint64) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(typ))
+	if data != nil {
+		b = appendBytesField(b, 2, data)
+	}
+	if filesize != nil {
+		b = appendVarintField(b, 3, *filesize)
+	}
+	for _, s := range blocksizes {
+		b = appendVarintField(b, 4, s)
+	}
+	return b
+}
+
+// Data is a decoded UnixFS Data message.
+type Data struct {
+	Type       DataType
+	Data       []byte
+	FileSize   *uint64
+	BlockSizes []uint64
+}
+
+// DecodeData decodes a UnixFS Data protobuf message.
+func DecodeData(b []byte) (Data, error) {
+	var d Data
+	err := readFields(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == wireVarint:
+			d.Type = DataType(v)
+		case field == 2 && wire == wireBytes:
+			d.Data = data
+		case field == 3 && wire == wireVarint:
+			d.FileSize = &v
+		case field == 4 && wire == wireVarint:
+			d.BlockSizes = append(d.BlockSizes, v)
+		case field == 4 && wire == wireBytes:
+			// packed encoding
+			for len(data) > 0 {
+				s, n := binary.Uvarint(data)
+				if n <= 0 {
+					return errors.New("invalid packed blocksizes")
+				}
+				d.BlockSizes = append(d.BlockSizes, s)
+				data = data[n:]
+			}
+		default:
+			// ignore hashType, fanout, mode, mtime
+		}
+		return nil
+	})
+	if err != nil {
+		return Data{}, err
+	}
+	return d, nil
+}